@@ -0,0 +1,152 @@
+// Credentials and request signing for DynamoDB
+//
+// Credentials are looked up, in order, in the environment, in the shared
+// credentials and config files, from a web identity token, from the
+// container credentials endpoint and from the instance metadata service.
+// Temporary credentials are refreshed before they expire.
+//
+//	s := &dynamodb.Service{
+//		Region:  "eu-west-1",
+//		Version: "20120810",
+//		Client:  credentials.NewSigner(credentials.NewCache(&credentials.SharedFile{Profile: "production"})),
+//	}
+package credentials
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNoCredentials = errors.New("dynamodb: no credentials found")
+)
+
+var (
+	DefaultSigner = NewSigner(NewCache(DefaultChain()))
+)
+
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// Expiration time of temporary credentials, zero for credentials that
+	// never expire.
+	Expires time.Time
+}
+
+// Reports whether the credentials expire within the given duration.
+func (c Credentials) expired(window time.Duration) bool {
+	return !c.Expires.IsZero() && !time.Now().Add(window).Before(c.Expires)
+}
+
+// A Provider retrieves credentials, returning ErrNoCredentials when it
+// isn't configured.
+type Provider interface {
+	Retrieve(ctx context.Context) (Credentials, error)
+}
+
+// Static provides the same credentials.
+type Static Credentials
+
+func (s Static) Retrieve(ctx context.Context) (Credentials, error) {
+	if s.AccessKeyID == "" || s.SecretAccessKey == "" {
+		return Credentials{}, ErrNoCredentials
+	}
+	return Credentials(s), nil
+}
+
+// Environment provides credentials from the AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN environment variables.
+type Environment struct{}
+
+func (Environment) Retrieve(ctx context.Context) (Credentials, error) {
+	c := Credentials{
+		AccessKeyID:     getenv("AWS_ACCESS_KEY_ID", "AWS_ACCESS_KEY"),
+		SecretAccessKey: getenv("AWS_SECRET_ACCESS_KEY", "AWS_SECRET_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	return Static(c).Retrieve(ctx)
+}
+
+// Returns the value of the first of the given environment variables set.
+func getenv(keys ...string) string {
+	for _, key := range keys {
+		if v := os.Getenv(key); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// A Chain provides the credentials of the first of its providers that is
+// configured.
+type Chain []Provider
+
+// Returns the chain of providers looking up credentials in the
+// environment, the shared files, a web identity token file, the container
+// endpoint and the instance metadata service.
+func DefaultChain() Chain {
+	return Chain{
+		Environment{},
+		&SharedFile{},
+		&WebIdentity{},
+		&Container{},
+		&InstanceMetadata{},
+	}
+}
+
+func (c Chain) Retrieve(ctx context.Context) (Credentials, error) {
+	for _, p := range c {
+		creds, err := p.Retrieve(ctx)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+		return creds, err
+	}
+	return Credentials{}, ErrNoCredentials
+}
+
+// A Cache retrieves credentials once, and again when they are about to
+// expire.
+type Cache struct {
+	Provider Provider
+	// Duration before the expiration of credentials from which they are
+	// refreshed.
+	ExpiryWindow time.Duration
+
+	mu    sync.Mutex
+	creds *Credentials
+}
+
+// Returns a cache of the given provider, refreshing credentials five
+// minutes before they expire.
+func NewCache(p Provider) *Cache {
+	return &Cache{
+		Provider:     p,
+		ExpiryWindow: 5 * time.Minute,
+	}
+}
+
+func (c *Cache) Retrieve(ctx context.Context) (Credentials, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.creds != nil && !c.creds.expired(c.ExpiryWindow) {
+		return *c.creds, nil
+	}
+	creds, err := c.Provider.Retrieve(ctx)
+	if err != nil {
+		return Credentials{}, err
+	}
+	c.creds = &creds
+	return creds, nil
+}
+
+// Forces credentials to be retrieved again, e.g. after they were revoked.
+func (c *Cache) Expire() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.creds = nil
+}
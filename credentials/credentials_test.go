@@ -0,0 +1,154 @@
+package credentials
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Clears the environment variables read by providers.
+func clearenv(t *testing.T) {
+	for _, key := range []string{
+		"AWS_ACCESS_KEY_ID", "AWS_ACCESS_KEY", "AWS_SECRET_ACCESS_KEY", "AWS_SECRET_KEY", "AWS_SESSION_TOKEN",
+		"AWS_PROFILE", "AWS_DEFAULT_PROFILE", "AWS_SHARED_CREDENTIALS_FILE", "AWS_CONFIG_FILE",
+		"AWS_ROLE_ARN", "AWS_WEB_IDENTITY_TOKEN_FILE", "AWS_ROLE_SESSION_NAME", "AWS_REGION", "AWS_DEFAULT_REGION",
+		"AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", "AWS_CONTAINER_CREDENTIALS_FULL_URI",
+		"AWS_CONTAINER_AUTHORIZATION_TOKEN", "AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE",
+		"AWS_EC2_METADATA_SERVICE_ENDPOINT", "AWS_EC2_METADATA_DISABLED",
+	} {
+		t.Setenv(key, "")
+	}
+	t.Setenv("HOME", t.TempDir())
+}
+
+func write(t *testing.T, name, content string) string {
+	filename := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(filename, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestEnvironment(t *testing.T) {
+	clearenv(t)
+	if _, err := (Environment{}).Retrieve(context.Background()); err != ErrNoCredentials {
+		t.Errorf("expected no credentials, got %v", err)
+	}
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "token")
+	c, err := (Environment{}).Retrieve(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c != (Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret", SessionToken: "token"}) {
+		t.Errorf("unexpected credentials %+v", c)
+	}
+}
+
+func TestSharedFile(t *testing.T) {
+	clearenv(t)
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", write(t, "credentials", `
+[default]
+aws_access_key_id = AKID
+aws_secret_access_key = secret
+
+# Temporary credentials.
+[staging]
+aws_access_key_id=AKIDSTAGING
+aws_secret_access_key=staging
+aws_session_token=token
+`))
+	t.Setenv("AWS_CONFIG_FILE", write(t, "config", `
+[default]
+region = eu-west-1
+
+[profile  production]
+aws_access_key_id = AKIDPRODUCTION
+aws_secret_access_key = production
+`))
+
+	for profile, expected := range map[string]Credentials{
+		"":           {AccessKeyID: "AKID", SecretAccessKey: "secret"},
+		"staging":    {AccessKeyID: "AKIDSTAGING", SecretAccessKey: "staging", SessionToken: "token"},
+		"production": {AccessKeyID: "AKIDPRODUCTION", SecretAccessKey: "production"},
+	} {
+		t.Setenv("AWS_PROFILE", profile)
+		c, err := (&SharedFile{}).Retrieve(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c != expected {
+			t.Errorf("expected %+v for profile %q, got %+v", expected, profile, c)
+		}
+	}
+
+	if _, err := (&SharedFile{Profile: "development"}).Retrieve(context.Background()); err == nil || err == ErrNoCredentials {
+		t.Errorf("expected a missing profile, got %v", err)
+	}
+	t.Setenv("AWS_PROFILE", "")
+	if _, err := (&SharedFile{Filename: "missing", ConfigFilename: "missing"}).Retrieve(context.Background()); err != ErrNoCredentials {
+		t.Errorf("expected no credentials, got %v", err)
+	}
+}
+
+func TestChain(t *testing.T) {
+	clearenv(t)
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	if _, err := DefaultChain().Retrieve(context.Background()); err != ErrNoCredentials {
+		t.Errorf("expected no credentials, got %v", err)
+	}
+
+	failure := errors.New("failure")
+	chain := Chain{
+		Static{},
+		Static{AccessKeyID: "AKID", SecretAccessKey: "secret"},
+		Static{AccessKeyID: "AKIDOTHER", SecretAccessKey: "other"},
+	}
+	c, err := chain.Retrieve(context.Background())
+	if err != nil || c.AccessKeyID != "AKID" {
+		t.Errorf("expected the first credentials, got %+v (%v)", c, err)
+	}
+	chain = Chain{provider(func() (Credentials, error) { return Credentials{}, failure }), chain[1]}
+	if _, err := chain.Retrieve(context.Background()); err != failure {
+		t.Errorf("expected a failure, got %v", err)
+	}
+}
+
+type provider func() (Credentials, error)
+
+func (p provider) Retrieve(ctx context.Context) (Credentials, error) {
+	return p()
+}
+
+func TestCache(t *testing.T) {
+	n := 0
+	expires := time.Now().Add(time.Hour)
+	c := NewCache(provider(func() (Credentials, error) {
+		n++
+		return Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret", Expires: expires}, nil
+	}))
+	for i := 0; i < 3; i++ {
+		if _, err := c.Retrieve(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n != 1 {
+		t.Errorf("expected credentials to be retrieved once, got %d", n)
+	}
+
+	// Credentials expiring within the window are refreshed.
+	expires = time.Now().Add(time.Minute)
+	c.Expire()
+	for i := 0; i < 3; i++ {
+		if _, err := c.Retrieve(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n != 4 {
+		t.Errorf("expected credentials to be refreshed, got %d retrievals", n)
+	}
+}
@@ -0,0 +1,318 @@
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	containerHost = "http://169.254.170.2"
+	metadataHost  = "http://169.254.169.254"
+)
+
+// WebIdentity provides temporary credentials of a role, assumed with a web
+// identity token file, as done by Kubernetes service accounts.
+type WebIdentity struct {
+	// ARN of the role, AWS_ROLE_ARN when empty.
+	RoleARN string
+	// Path of the token file, AWS_WEB_IDENTITY_TOKEN_FILE when empty.
+	TokenFile string
+	// Name of the session, AWS_ROLE_SESSION_NAME when empty, or generated.
+	SessionName string
+	// URL of the security token service, regional when AWS_REGION is set.
+	Endpoint string
+	// Client used to assume the role, http.DefaultClient when nil.
+	Client *http.Client
+}
+
+func (w *WebIdentity) Retrieve(ctx context.Context) (Credentials, error) {
+	role := w.RoleARN
+	if role == "" {
+		role = os.Getenv("AWS_ROLE_ARN")
+	}
+	filename := w.TokenFile
+	if filename == "" {
+		filename = os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE")
+	}
+	if role == "" || filename == "" {
+		return Credentials{}, ErrNoCredentials
+	}
+	session := w.SessionName
+	if session == "" {
+		session = os.Getenv("AWS_ROLE_SESSION_NAME")
+	}
+	if session == "" {
+		session = fmt.Sprintf("dynamodb-%d", time.Now().UnixNano())
+	}
+	endpoint := w.Endpoint
+	if endpoint == "" {
+		endpoint = "https://sts.amazonaws.com/"
+		if region := getenv("AWS_REGION", "AWS_DEFAULT_REGION"); region != "" {
+			endpoint = fmt.Sprintf("https://sts.%s.amazonaws.com/", region)
+		}
+	}
+
+	token, err := os.ReadFile(filename)
+	if err != nil {
+		return Credentials{}, err
+	}
+	form := url.Values{
+		"Action":           {"AssumeRoleWithWebIdentity"},
+		"Version":          {"2011-06-15"},
+		"RoleArn":          {role},
+		"RoleSessionName":  {session},
+		"WebIdentityToken": {strings.TrimSpace(string(token))},
+	}
+	r, err := http.NewRequestWithContext(ctx, "POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Credentials{}, err
+	}
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("Accept", "application/xml")
+
+	resp, err := client(w.Client).Do(r)
+	if err != nil {
+		return Credentials{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Error struct {
+				Code    string
+				Message string
+			}
+		}
+		xml.NewDecoder(resp.Body).Decode(&e)
+		return Credentials{}, fmt.Errorf("dynamodb: can't assume role %s: %s: %s", role, e.Error.Code, e.Error.Message)
+	}
+	var a struct {
+		Credentials struct {
+			AccessKeyId     string
+			SecretAccessKey string
+			SessionToken    string
+			Expiration      time.Time
+		} `xml:"AssumeRoleWithWebIdentityResult>Credentials"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&a); err != nil {
+		return Credentials{}, err
+	}
+	return Credentials{
+		AccessKeyID:     a.Credentials.AccessKeyId,
+		SecretAccessKey: a.Credentials.SecretAccessKey,
+		SessionToken:    a.Credentials.SessionToken,
+		Expires:         a.Credentials.Expiration,
+	}, nil
+}
+
+// Container provides the credentials of the task role of ECS tasks, or of
+// the pod identity of EKS pods.
+type Container struct {
+	// URL of the credentials, derived from the
+	// AWS_CONTAINER_CREDENTIALS_RELATIVE_URI or
+	// AWS_CONTAINER_CREDENTIALS_FULL_URI environment variables when empty.
+	// It must use https, or be local or the ECS or EKS endpoint.
+	Endpoint string
+	// Authorization token, read from AWS_CONTAINER_AUTHORIZATION_TOKEN, or
+	// from the file named by AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE, when
+	// empty.
+	Token string
+	// Client used to fetch credentials, http.DefaultClient when nil.
+	Client *http.Client
+}
+
+func (c *Container) Retrieve(ctx context.Context) (Credentials, error) {
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		if uri := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); uri != "" {
+			endpoint = containerHost + uri
+		} else {
+			endpoint = os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI")
+		}
+	}
+	if endpoint == "" {
+		return Credentials{}, ErrNoCredentials
+	}
+	if err := containerEndpoint(endpoint); err != nil {
+		return Credentials{}, err
+	}
+	token := c.Token
+	if token == "" {
+		token = os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN")
+	}
+	if filename := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE"); token == "" && filename != "" {
+		// The file is read each time, as the token is rotated.
+		b, err := os.ReadFile(filename)
+		if err != nil {
+			return Credentials{}, err
+		}
+		token = strings.TrimSpace(string(b))
+	}
+
+	r, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return Credentials{}, err
+	}
+	r.Header.Set("Accept", "application/json")
+	if token != "" {
+		r.Header.Set("Authorization", token)
+	}
+	return fetch(client(c.Client), r)
+}
+
+// Hosts of the ECS and EKS credentials endpoints.
+var containerHosts = []string{"169.254.170.2", "169.254.170.23", "fd00:ec2::23"}
+
+// Checks the authorization token is only sent over https, or to the
+// loopback interface or the ECS and EKS hosts.
+func containerEndpoint(endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return err
+	}
+	if u.Scheme == "https" {
+		return nil
+	}
+	host := u.Hostname()
+	ip := net.ParseIP(host)
+	if host == "localhost" || ip.IsLoopback() {
+		return nil
+	}
+	for _, h := range containerHosts {
+		if ip.Equal(net.ParseIP(h)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("dynamodb: container credentials endpoint %s is neither https nor local", endpoint)
+}
+
+// InstanceMetadata provides the credentials of the role of EC2 instances,
+// using the version 2 of the instance metadata service.
+type InstanceMetadata struct {
+	// URL of the service, AWS_EC2_METADATA_SERVICE_ENDPOINT or
+	// http://169.254.169.254 when empty.
+	Endpoint string
+	// Client used to fetch credentials, with a short timeout when nil.
+	Client *http.Client
+}
+
+func (m *InstanceMetadata) Retrieve(ctx context.Context) (Credentials, error) {
+	if strings.EqualFold(os.Getenv("AWS_EC2_METADATA_DISABLED"), "true") {
+		return Credentials{}, ErrNoCredentials
+	}
+	endpoint := m.Endpoint
+	if endpoint == "" {
+		endpoint = os.Getenv("AWS_EC2_METADATA_SERVICE_ENDPOINT")
+	}
+	if endpoint == "" {
+		endpoint = metadataHost
+	}
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	c := m.Client
+	if c == nil {
+		c = &http.Client{Timeout: time.Second}
+	}
+
+	r, err := http.NewRequestWithContext(ctx, "PUT", endpoint+"/latest/api/token", nil)
+	if err != nil {
+		return Credentials{}, err
+	}
+	r.Header.Set("X-Aws-Ec2-Metadata-Token-Ttl-Seconds", "21600")
+	resp, err := c.Do(r)
+	if err != nil {
+		// Most likely not running on an instance.
+		return Credentials{}, fmt.Errorf("%w: %v", ErrNoCredentials, err)
+	}
+	token, err := read(resp)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	r, err = http.NewRequestWithContext(ctx, "GET", endpoint+"/latest/meta-data/iam/security-credentials/", nil)
+	if err != nil {
+		return Credentials{}, err
+	}
+	r.Header.Set("X-Aws-Ec2-Metadata-Token", token)
+	resp, err = c.Do(r)
+	if err != nil {
+		return Credentials{}, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		// The instance has no role.
+		return Credentials{}, ErrNoCredentials
+	}
+	role, err := read(resp)
+	if err != nil {
+		return Credentials{}, err
+	}
+	role, _, _ = strings.Cut(role, "\n")
+
+	r, err = http.NewRequestWithContext(ctx, "GET", endpoint+"/latest/meta-data/iam/security-credentials/"+url.PathEscape(role), nil)
+	if err != nil {
+		return Credentials{}, err
+	}
+	r.Header.Set("X-Aws-Ec2-Metadata-Token", token)
+	return fetch(c, r)
+}
+
+func client(c *http.Client) *http.Client {
+	if c == nil {
+		return http.DefaultClient
+	}
+	return c
+}
+
+// Returns the body of the given response, which must be successful.
+func read(resp *http.Response) (string, error) {
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("dynamodb: %s %s: %s", resp.Request.Method, resp.Request.URL.Path, resp.Status)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// Fetches the credentials returned, in JSON, by the given request.
+func fetch(c *http.Client, r *http.Request) (Credentials, error) {
+	resp, err := c.Do(r)
+	if err != nil {
+		return Credentials{}, err
+	}
+	b, err := read(resp)
+	if err != nil {
+		return Credentials{}, err
+	}
+	var a struct {
+		Code            string
+		Message         string
+		AccessKeyId     string
+		SecretAccessKey string
+		Token           string
+		Expiration      time.Time
+	}
+	if err := json.Unmarshal([]byte(b), &a); err != nil {
+		return Credentials{}, err
+	}
+	if a.Code != "" && a.Code != "Success" {
+		return Credentials{}, fmt.Errorf("dynamodb: can't fetch credentials: %s: %s", a.Code, a.Message)
+	}
+	return Credentials{
+		AccessKeyID:     a.AccessKeyId,
+		SecretAccessKey: a.SecretAccessKey,
+		SessionToken:    a.Token,
+		Expires:         a.Expiration,
+	}, nil
+}
@@ -0,0 +1,150 @@
+package credentials
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var expires = time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+const credentials = `{
+	"Code": "Success",
+	"AccessKeyId": "AKID",
+	"SecretAccessKey": "secret",
+	"Token": "token",
+	"Expiration": "2030-01-01T00:00:00Z"
+}`
+
+func expected(t *testing.T, c Credentials, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c != (Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret", SessionToken: "token", Expires: expires}) {
+		t.Errorf("unexpected credentials %+v", c)
+	}
+}
+
+func TestWebIdentity(t *testing.T) {
+	clearenv(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("Action") != "AssumeRoleWithWebIdentity" || r.Form.Get("WebIdentityToken") != "jwt" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `<ErrorResponse><Error><Code>InvalidIdentityToken</Code><Message>invalid</Message></Error></ErrorResponse>`)
+			return
+		}
+		fmt.Fprintf(w, `<AssumeRoleWithWebIdentityResponse>
+  <AssumeRoleWithWebIdentityResult>
+    <Credentials>
+      <AccessKeyId>AKID</AccessKeyId>
+      <SecretAccessKey>secret</SecretAccessKey>
+      <SessionToken>token</SessionToken>
+      <Expiration>2030-01-01T00:00:00Z</Expiration>
+    </Credentials>
+  </AssumeRoleWithWebIdentityResult>
+</AssumeRoleWithWebIdentityResponse>`)
+	}))
+	defer srv.Close()
+
+	w := &WebIdentity{Endpoint: srv.URL}
+	if _, err := w.Retrieve(context.Background()); err != ErrNoCredentials {
+		t.Errorf("expected no credentials, got %v", err)
+	}
+	t.Setenv("AWS_ROLE_ARN", "arn:aws:iam::123456789012:role/papers")
+	t.Setenv("AWS_WEB_IDENTITY_TOKEN_FILE", write(t, "token", "jwt\n"))
+	c, err := w.Retrieve(context.Background())
+	expected(t, c, err)
+
+	// Profiles can assume roles too.
+	t.Setenv("AWS_ROLE_ARN", "")
+	t.Setenv("AWS_CONFIG_FILE", write(t, "config", fmt.Sprintf(`
+[profile papers]
+role_arn = arn:aws:iam::123456789012:role/papers
+web_identity_token_file = %s
+`, write(t, "token", "invalid"))))
+	_, err = (&SharedFile{Profile: "papers"}).Retrieve(context.Background())
+	if err == nil {
+		t.Errorf("expected an invalid token")
+	}
+}
+
+func TestContainer(t *testing.T) {
+	clearenv(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/credentials" || r.Header.Get("Authorization") != "secret-token" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		fmt.Fprint(w, credentials)
+	}))
+	defer srv.Close()
+
+	c := &Container{}
+	if _, err := c.Retrieve(context.Background()); err != ErrNoCredentials {
+		t.Errorf("expected no credentials, got %v", err)
+	}
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_FULL_URI", srv.URL+"/v2/credentials")
+	if _, err := c.Retrieve(context.Background()); err == nil {
+		t.Errorf("expected an unauthorized request")
+	}
+	t.Setenv("AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE", write(t, "token", "secret-token\n"))
+	creds, err := c.Retrieve(context.Background())
+	expected(t, creds, err)
+
+	for _, endpoint := range []string{"http://example.com/credentials", "http://169.254.169.254/credentials"} {
+		if _, err := (&Container{Endpoint: endpoint, Token: "secret-token"}).Retrieve(context.Background()); err == nil {
+			t.Errorf("expected %s to be rejected", endpoint)
+		}
+	}
+	for _, endpoint := range []string{"https://example.com", "http://localhost:8080", "http://[::1]", "http://169.254.170.23/v1/credentials"} {
+		if err := containerEndpoint(endpoint); err != nil {
+			t.Errorf("expected %s to be accepted, got %v", endpoint, err)
+		}
+	}
+
+	// A default profile holding only settings is skipped.
+	t.Setenv("AWS_CONFIG_FILE", write(t, "config", "[default]\nregion = eu-west-1\n"))
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	creds, err = DefaultChain().Retrieve(context.Background())
+	expected(t, creds, err)
+	if _, err := (&SharedFile{Profile: "default"}).Retrieve(context.Background()); err == nil || err == ErrNoCredentials {
+		t.Errorf("expected a profile without keys, got %v", err)
+	}
+}
+
+func TestInstanceMetadata(t *testing.T) {
+	clearenv(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" && r.URL.Path == "/latest/api/token" {
+			fmt.Fprint(w, "session")
+			return
+		}
+		if r.Header.Get("X-Aws-Ec2-Metadata-Token") != "session" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/latest/meta-data/iam/security-credentials/":
+			fmt.Fprint(w, "papers\n")
+		case "/latest/meta-data/iam/security-credentials/papers":
+			fmt.Fprint(w, credentials)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	t.Setenv("AWS_EC2_METADATA_SERVICE_ENDPOINT", srv.URL)
+	c, err := (&InstanceMetadata{}).Retrieve(context.Background())
+	expected(t, c, err)
+
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	if _, err := (&InstanceMetadata{}).Retrieve(context.Background()); err != ErrNoCredentials {
+		t.Errorf("expected no credentials, got %v", err)
+	}
+}
@@ -0,0 +1,154 @@
+package credentials
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SharedFile provides credentials of a profile of the shared credentials
+// and config files. Profiles of the config file can also assume a role
+// with a web identity token file.
+type SharedFile struct {
+	// Path of the credentials file, AWS_SHARED_CREDENTIALS_FILE or
+	// ~/.aws/credentials when empty.
+	Filename string
+	// Path of the config file, AWS_CONFIG_FILE or ~/.aws/config when empty.
+	ConfigFilename string
+	// Name of the profile, AWS_PROFILE or "default" when empty.
+	Profile string
+	// Client used to assume roles, http.DefaultClient when nil.
+	Client *http.Client
+}
+
+func (s *SharedFile) Retrieve(ctx context.Context) (Credentials, error) {
+	profile := s.Profile
+	if profile == "" {
+		profile = getenv("AWS_PROFILE", "AWS_DEFAULT_PROFILE")
+	}
+	explicit := profile != ""
+	if !explicit {
+		profile = "default"
+	}
+
+	filename := s.Filename
+	if filename == "" {
+		filename = home("AWS_SHARED_CREDENTIALS_FILE", "credentials")
+	}
+	credentials, err := parseFile(filename)
+	if err != nil {
+		return Credentials{}, err
+	}
+	filename = s.ConfigFilename
+	if filename == "" {
+		filename = home("AWS_CONFIG_FILE", "config")
+	}
+	config, err := parseFile(filename)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	// Keys of the credentials file take precedence over the config file.
+	section := "profile " + profile
+	if profile == "default" {
+		if _, present := config[section]; !present {
+			section = profile
+		}
+	}
+	values, present := config[section]
+	if values == nil {
+		values = make(map[string]string)
+	}
+	for k, v := range credentials[profile] {
+		values[k] = v
+	}
+	if _, found := credentials[profile]; !present && !found {
+		if explicit {
+			return Credentials{}, fmt.Errorf("dynamodb: profile %s not found", profile)
+		}
+		return Credentials{}, ErrNoCredentials
+	}
+
+	if values["web_identity_token_file"] != "" {
+		w := &WebIdentity{
+			RoleARN:     values["role_arn"],
+			TokenFile:   values["web_identity_token_file"],
+			SessionName: values["role_session_name"],
+			Client:      s.Client,
+		}
+		return w.Retrieve(ctx)
+	}
+	c := Credentials{
+		AccessKeyID:     values["aws_access_key_id"],
+		SecretAccessKey: values["aws_secret_access_key"],
+		SessionToken:    values["aws_session_token"],
+	}
+	if c.AccessKeyID == "" || c.SecretAccessKey == "" {
+		if !explicit {
+			// The default profile may only hold settings, e.g. the region.
+			return Credentials{}, ErrNoCredentials
+		}
+		return Credentials{}, fmt.Errorf("dynamodb: profile %s has no access key", profile)
+	}
+	return c, nil
+}
+
+// Returns the path given by the environment variable, or the given file
+// of the ~/.aws directory.
+func home(key, name string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	dir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, ".aws", name)
+}
+
+// Parses the sections of the given INI file, which is considered empty
+// when missing.
+func parseFile(filename string) (map[string]map[string]string, error) {
+	sections := make(map[string]map[string]string)
+	if filename == "" {
+		return sections, nil
+	}
+	f, err := os.Open(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return sections, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var values map[string]string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || line[0] == '#' || line[0] == ';':
+		case line[0] == '[' && line[len(line)-1] == ']':
+			name := strings.Join(strings.Fields(line[1:len(line)-1]), " ")
+			values = sections[name]
+			if values == nil {
+				values = make(map[string]string)
+				sections[name] = values
+			}
+		default:
+			k, v, found := strings.Cut(line, "=")
+			if !found || values == nil {
+				// Ignore keys outside of sections and nested values.
+				continue
+			}
+			values[strings.ToLower(strings.TrimSpace(k))] = strings.TrimSpace(v)
+		}
+	}
+	return sections, scanner.Err()
+}
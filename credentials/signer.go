@@ -0,0 +1,177 @@
+package credentials
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	algorithm  = "AWS4-HMAC-SHA256"
+	timeFormat = "20060102T150405Z"
+)
+
+// A Signer sends requests signed with Signature Version 4, using the
+// credentials of its provider.
+type Signer struct {
+	Credentials Provider
+	// Region of the requests, derived from their host when empty.
+	Region string
+	// Name of the signed service, "dynamodb" when empty, which DynamoDB
+	// Streams shares.
+	Service string
+	// Client sending the signed requests, http.DefaultClient when nil.
+	Client *http.Client
+}
+
+// Returns a signer for DynamoDB using the given credentials.
+func NewSigner(p Provider) *Signer {
+	return &Signer{
+		Credentials: p,
+	}
+}
+
+// Signs and sends the given request.
+func (s *Signer) Do(r *http.Request) (*http.Response, error) {
+	creds, err := s.Credentials.Retrieve(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	var body []byte
+	if r.Body != nil {
+		body, err = io.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	r = r.Clone(r.Context())
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	region := s.Region
+	if region == "" {
+		region = hostRegion(r.URL.Hostname())
+	}
+	service := s.Service
+	if service == "" {
+		service = "dynamodb"
+	}
+	Sign(r, body, creds, region, service, time.Now())
+	return client(s.Client).Do(r)
+}
+
+// Returns the region of AWS hosts, such as dynamodb.eu-west-1.amazonaws.com.
+func hostRegion(host string) string {
+	labels := strings.Split(host, ".")
+	for i := 1; i < len(labels); i++ {
+		if labels[i] == "amazonaws" {
+			return labels[i-1]
+		}
+	}
+	return "us-east-1"
+}
+
+// Sets the authorization headers of the given request, whose body is
+// given, signed at the given time for the given region and service.
+func Sign(r *http.Request, body []byte, c Credentials, region, service string, t time.Time) {
+	t = t.UTC()
+	r.Header.Set("X-Amz-Date", t.Format(timeFormat))
+	if c.SessionToken != "" {
+		r.Header.Set("X-Amz-Security-Token", c.SessionToken)
+	}
+	host := r.Host
+	if host == "" {
+		host = r.URL.Host
+	}
+
+	// Signs the host, the content type and every AWS header.
+	headers := map[string]string{"host": host}
+	for k, v := range r.Header {
+		k = strings.ToLower(k)
+		if k == "content-type" || strings.HasPrefix(k, "x-amz-") {
+			values := make([]string, len(v))
+			for i := range v {
+				values[i] = strings.Join(strings.Fields(v[i]), " ")
+			}
+			headers[k] = strings.Join(values, ",")
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonical strings.Builder
+	for _, k := range names {
+		fmt.Fprintf(&canonical, "%s:%s\n", k, headers[k])
+	}
+	signed := strings.Join(names, ";")
+
+	path := r.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	request := strings.Join([]string{
+		r.Method,
+		path,
+		canonicalQuery(r),
+		canonical.String(),
+		signed,
+		hash(body),
+	}, "\n")
+
+	date := t.Format("20060102")
+	scope := strings.Join([]string{date, region, service, "aws4_request"}, "/")
+	toSign := strings.Join([]string{algorithm, t.Format(timeFormat), scope, hash([]byte(request))}, "\n")
+
+	key := []byte("AWS4" + c.SecretAccessKey)
+	for _, s := range []string{date, region, service, "aws4_request"} {
+		key = mac(key, s)
+	}
+	signature := hex.EncodeToString(mac(key, toSign))
+
+	r.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		algorithm, c.AccessKeyID, scope, signed, signature))
+}
+
+// Returns the query string with sorted and strictly escaped parameters.
+func canonicalQuery(r *http.Request) string {
+	var params []string
+	for k, values := range r.URL.Query() {
+		for _, v := range values {
+			params = append(params, escape(k)+"="+escape(v))
+		}
+	}
+	sort.Strings(params)
+	return strings.Join(params, "&")
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hash(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+func mac(key []byte, s string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(s))
+	return h.Sum(nil)
+}
@@ -0,0 +1,65 @@
+package credentials
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	c := Credentials{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}
+	date := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+
+	// Examples of the AWS Signature Version 4 test suite.
+	for method, signature := range map[string]string{
+		"GET":  "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		"POST": "5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b",
+	} {
+		r, _ := http.NewRequest(method, "https://example.amazonaws.com/", nil)
+		Sign(r, nil, c, "us-east-1", "service", date)
+		expected := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=" + signature
+		if got := r.Header.Get("Authorization"); got != expected {
+			t.Errorf("expected %s signature %q, got %q", method, expected, got)
+		}
+	}
+}
+
+func TestSigner(t *testing.T) {
+	var signed *http.Request
+	s := NewSigner(Static{AccessKeyID: "AKID", SecretAccessKey: "secret", SessionToken: "token"})
+	s.Client = &http.Client{
+		Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			signed = r
+			return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader("{}")), Request: r}, nil
+		}),
+	}
+	r, _ := http.NewRequestWithContext(context.Background(), "POST", "https://dynamodb.eu-west-1.amazonaws.com/", strings.NewReader("{}"))
+	r.Header.Set("Content-Type", "application/x-amz-json-1.0")
+	r.Header.Set("X-Amz-Target", "DynamoDB_20120810.ListTables")
+	if _, err := s.Do(r); err != nil {
+		t.Fatal(err)
+	}
+
+	auth := signed.Header.Get("Authorization")
+	if !strings.Contains(auth, "/eu-west-1/dynamodb/aws4_request") || !strings.Contains(auth, "SignedHeaders=content-type;host;x-amz-date;x-amz-security-token;x-amz-target,") {
+		t.Errorf("unexpected authorization %q", auth)
+	}
+	if signed.Header.Get("X-Amz-Security-Token") != "token" {
+		t.Errorf("expected the session token to be sent")
+	}
+	if b, _ := io.ReadAll(signed.Body); string(b) != "{}" {
+		t.Errorf("expected the body to be sent, got %q", b)
+	}
+}
+
+type roundTripFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
@@ -0,0 +1,163 @@
+package dynamodb
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/cyberdelia/dynamodb/types"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidCursor = errors.New("dynamodb: invalid cursor")
+	ErrExpiredCursor = errors.New("dynamodb: expired cursor")
+)
+
+var cursorEncoding = base64.RawURLEncoding
+
+type cursor struct {
+	Key     types.AttributeValue
+	Expires int64 `json:",omitempty"`
+}
+
+// Encodes the given key into an opaque and URL-safe cursor, signed with
+// the given secret unless it's empty. The scope identifies what the cursor
+// pages through, such as a table, the cursor only being decoded with the
+// same scope, until it expires unless expires is zero. A nil key gives an
+// empty cursor.
+func EncodeCursor(key types.AttributeValue, scope string, expires time.Time, secret []byte) (string, error) {
+	if len(key) == 0 {
+		return "", nil
+	}
+	c := cursor{Key: key}
+	if !expires.IsZero() {
+		c.Expires = expires.Unix()
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	encoded := cursorEncoding.EncodeToString(b)
+	if len(secret) == 0 {
+		return encoded, nil
+	}
+	return encoded + "." + cursorEncoding.EncodeToString(sign(scope, b, secret)), nil
+}
+
+// Decodes the key from the given cursor of the given scope, verifying its
+// signature with the given secret unless it's empty. An empty cursor
+// gives a nil key.
+func DecodeCursor(encoded string, scope string, secret []byte) (types.AttributeValue, error) {
+	if encoded == "" {
+		return nil, nil
+	}
+	payload, signature, signed := strings.Cut(encoded, ".")
+	if signed != (len(secret) > 0) {
+		return nil, ErrInvalidCursor
+	}
+	b, err := cursorEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if signed {
+		mac, err := cursorEncoding.DecodeString(signature)
+		if err != nil || !hmac.Equal(mac, sign(scope, b, secret)) {
+			return nil, ErrInvalidCursor
+		}
+	}
+	var c cursor
+	if err := json.Unmarshal(b, &c); err != nil || len(c.Key) == 0 {
+		return nil, ErrInvalidCursor
+	}
+	if c.Expires != 0 && time.Now().Unix() >= c.Expires {
+		return nil, ErrExpiredCursor
+	}
+	return c.Key, nil
+}
+
+func sign(scope string, b, secret []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(scope))
+	mac.Write([]byte{0})
+	mac.Write(b)
+	return mac.Sum(nil)
+}
+
+// Returns the scope of cursors of the given action, table, index and key
+// condition values.
+func cursorScope(action, tableName, index string, values types.AttributeValue) (string, error) {
+	b, err := json.Marshal([]interface{}{action, tableName, index, values})
+	return string(b), err
+}
+
+// Return a single page of items from the given table, starting at the
+// given cursor, and the cursor of the next page, empty on the last page.
+func (s *Service) ScanPage(ctx context.Context, tableName string, item interface{}, cursor string, opts ...Option) (interface{}, string, error) {
+	o := newOptions(opts)
+	scope, err := cursorScope("Scan", tableName, o.index, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	return s.page(s.scan(ctx, tableName, o), item, scope, cursor)
+}
+
+// Return a single page of items from the given table, starting at the
+// given cursor, and the cursor of the next page, empty on the last page.
+func ScanPage(ctx context.Context, tableName string, item interface{}, cursor string, opts ...Option) (interface{}, string, error) {
+	return DefaultService.ScanPage(ctx, tableName, item, cursor, opts...)
+}
+
+// Return a single page of items sharing the hash key of the given item,
+// starting at the given cursor, and the cursor of the next page, empty on
+// the last page.
+func (s *Service) QueryPage(ctx context.Context, tableName string, item interface{}, cursor string, opts ...Option) (interface{}, string, error) {
+	o := newOptions(opts)
+	fetch, err := s.query(ctx, tableName, item, o)
+	if err != nil {
+		return nil, "", err
+	}
+	_, _, values, err := keyCondition(item, o.index)
+	if err != nil {
+		return nil, "", err
+	}
+	scope, err := cursorScope("Query", tableName, o.index, values)
+	if err != nil {
+		return nil, "", err
+	}
+	return s.page(fetch, item, scope, cursor)
+}
+
+// Return a single page of items sharing the hash key of the given item,
+// starting at the given cursor, and the cursor of the next page, empty on
+// the last page.
+func QueryPage(ctx context.Context, tableName string, item interface{}, cursor string, opts ...Option) (interface{}, string, error) {
+	return DefaultService.QueryPage(ctx, tableName, item, cursor, opts...)
+}
+
+func (s *Service) page(fetch fetchFunc, item interface{}, scope, cursor string) (interface{}, string, error) {
+	key, err := DecodeCursor(cursor, scope, s.CursorSecret)
+	if err != nil {
+		return nil, "", err
+	}
+	p, err := fetch(key)
+	if err != nil {
+		return nil, "", err
+	}
+	items, err := s.decoder().MakeSlice(p.Items, item)
+	if err != nil {
+		return nil, "", err
+	}
+	var expires time.Time
+	if s.CursorExpiry > 0 {
+		expires = time.Now().Add(s.CursorExpiry)
+	}
+	next, err := EncodeCursor(p.LastEvaluatedKey, scope, expires, s.CursorSecret)
+	if err != nil {
+		return nil, "", err
+	}
+	return items, next, nil
+}
@@ -0,0 +1,130 @@
+package dynamodb
+
+import (
+	"context"
+	"github.com/cyberdelia/dynamodb/types"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var cursorKey = types.AttributeValue{
+	"title": {"S": "Dynamo: Amazon’s Highly Available Key-value Store"},
+	"year":  {"N": "2007"},
+}
+
+func TestCursor(t *testing.T) {
+	for _, secret := range [][]byte{nil, []byte("secret")} {
+		cursor, err := EncodeCursor(cursorKey, "papers", time.Time{}, secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.ContainsAny(cursor, "+/=") {
+			t.Errorf("cursor %v isn't URL-safe", cursor)
+		}
+		key, err := DecodeCursor(cursor, "papers", secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(key, cursorKey) {
+			t.Errorf("got %v wants %v", key, cursorKey)
+		}
+	}
+}
+
+func TestCursorTampered(t *testing.T) {
+	cursor, err := EncodeCursor(cursorKey, "papers", time.Time{}, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, signature, _ := strings.Cut(cursor, ".")
+	forged, _ := EncodeCursor(types.AttributeValue{"title": {"S": "other"}}, "papers", time.Time{}, nil)
+	for _, c := range []string{
+		forged + "." + signature,
+		payload,
+		payload + ".",
+		"!!!",
+	} {
+		if _, err := DecodeCursor(c, "papers", []byte("secret")); err != ErrInvalidCursor {
+			t.Errorf("got %v wants %v for %v", err, ErrInvalidCursor, c)
+		}
+	}
+	if _, err := DecodeCursor(cursor, "papers", []byte("other")); err != ErrInvalidCursor {
+		t.Errorf("got %v wants %v", err, ErrInvalidCursor)
+	}
+	if _, err := DecodeCursor(cursor, "books", []byte("secret")); err != ErrInvalidCursor {
+		t.Errorf("got %v wants %v", err, ErrInvalidCursor)
+	}
+}
+
+func TestCursorExpired(t *testing.T) {
+	cursor, err := EncodeCursor(cursorKey, "papers", time.Now().Add(-time.Second), []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecodeCursor(cursor, "papers", []byte("secret")); err != ErrExpiredCursor {
+		t.Errorf("got %v wants %v", err, ErrExpiredCursor)
+	}
+}
+
+func TestQueryPageScope(t *testing.T) {
+	s := testService(func(action string, body map[string]interface{}) (int, interface{}) {
+		return 200, map[string]interface{}{
+			"Items": []interface{}{
+				map[string]interface{}{"title": map[string]string{"S": "a"}, "year": map[string]string{"N": "2007"}},
+			},
+			"LastEvaluatedKey": map[string]interface{}{"title": map[string]string{"S": "a"}, "year": map[string]string{"N": "2007"}},
+		}
+	})
+	s.CursorSecret = []byte("secret")
+	_, cursor, err := s.QueryPage(context.Background(), "papers", &paper{Title: "a"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.QueryPage(context.Background(), "papers", &paper{Title: "a"}, cursor); err != nil {
+		t.Errorf("got %v wants %v", err, nil)
+	}
+	if _, _, err := s.QueryPage(context.Background(), "papers", &paper{Title: "b"}, cursor); err != ErrInvalidCursor {
+		t.Errorf("got %v wants %v", err, ErrInvalidCursor)
+	}
+	if _, _, err := s.QueryPage(context.Background(), "books", &paper{Title: "a"}, cursor); err != ErrInvalidCursor {
+		t.Errorf("got %v wants %v", err, ErrInvalidCursor)
+	}
+	if _, _, err := s.ScanPage(context.Background(), "papers", &paper{}, cursor); err != ErrInvalidCursor {
+		t.Errorf("got %v wants %v", err, ErrInvalidCursor)
+	}
+}
+
+func TestScanPage(t *testing.T) {
+	s := testService(func(action string, body map[string]interface{}) (int, interface{}) {
+		if body["ExclusiveStartKey"] != nil {
+			return 200, map[string]interface{}{
+				"Items": []interface{}{
+					map[string]interface{}{"title": map[string]string{"S": "b"}},
+				},
+			}
+		}
+		return 200, map[string]interface{}{
+			"Items": []interface{}{
+				map[string]interface{}{"title": map[string]string{"S": "a"}},
+			},
+			"LastEvaluatedKey": map[string]interface{}{"title": map[string]string{"S": "a"}},
+		}
+	})
+	s.CursorSecret = []byte("secret")
+	items, cursor, err := s.ScanPage(context.Background(), "papers", &paper{}, "", Limit(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cursor == "" || items.([]*paper)[0].Title != "a" {
+		t.Fatalf("got %v and cursor %q", items, cursor)
+	}
+	items, cursor, err = s.ScanPage(context.Background(), "papers", &paper{}, cursor, Limit(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cursor != "" || items.([]*paper)[0].Title != "b" {
+		t.Fatalf("got %v and cursor %q", items, cursor)
+	}
+}
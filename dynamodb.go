@@ -19,34 +19,125 @@
 //   Field string `dynamo:",hash"`
 //   Field int    `dynamo:",range"`
 //
+//   // Field is a key of a global or local secondary index, whose
+//   // projection type can be given, and non-key attributes included.
+//   Field string `dynamo:",gsi=ByField:hash:keys_only"`
+//   Field int    `dynamo:",lsi=ByField:range"`
+//   Field string `dynamo:",include=ByField"`
+//
+//   // Field is the time to live attribute of the table, enabled by
+//   // EnsureTable, times are stored as seconds since the Unix epoch.
+//   Field time.Time `dynamo:",ttl"`
+//
+//   // Field collects attributes that don't map to any other field,
+//   // and writes them back when the item is stored.
+//   Field types.AttributeValue `dynamo:",inline"`
+//
+// Documents written by other clients are decoded as well: maps into
+// structs and maps, lists into slices, booleans into bools, and nulls
+// into zero values.
 package dynamodb
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	aws "github.com/bmizerany/aws4"
+	"github.com/cyberdelia/dynamodb/credentials"
 	"github.com/cyberdelia/dynamodb/types"
 	"net/http"
 	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Maximum number of keys in a single BatchGetItem request.
+const maxBatchGet = 100
+
+var (
+	ErrHashKey    = errors.New("dynamodb: item has no hash key value")
+	ErrNotFound   = errors.New("dynamodb: item not found")
+	ErrThroughput = errors.New("dynamodb: provisioned table has no throughput")
 )
 
 var (
 	DefaultService = &Service{
 		Region:  "us-east-1",
 		Version: "20120810",
-		Client:  aws.DefaultClient,
+		Client:  credentials.DefaultSigner,
 	}
 )
 
 type Service struct {
 	Region  string
 	Version string
-	Client  *aws.Client
+
+	// Client sends the requests, signing them for DynamoDB. It defaults
+	// to a signer using the default credentials chain, but any Doer can be
+	// used to instrument or fake requests.
+	Client Doer
+
+	// Strict makes reads fail on attributes that are unknown to, or
+	// mistyped for, the item they are decoded into.
+	Strict bool
+
+	// CursorSecret signs the cursors returned by ScanPage and QueryPage,
+	// which are left unsigned when empty. Signed cursors are only accepted
+	// for the table, index and key condition they were returned for.
+	CursorSecret []byte
+	// CursorExpiry limits the time cursors are accepted, unlimited when zero.
+	CursorExpiry time.Duration
+
+	// Middleware wraps every action performed, the first one being the
+	// outermost.
+	Middleware []Middleware
+}
+
+// A Doer sends HTTP requests, as *http.Client and *credentials.Signer do.
+type Doer interface {
+	Do(r *http.Request) (*http.Response, error)
+}
+
+// DoerFunc adapts a function into a Doer.
+type DoerFunc func(r *http.Request) (*http.Response, error)
+
+func (f DoerFunc) Do(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// An Error describes a request rejected by DynamoDB.
+type Error struct {
+	StatusCode int
+	Type       string // Exception name, e.g. "ResourceNotFoundException"
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Type, e.Message)
+}
+
+// Reports whether err is an Error of the given type.
+func IsError(err error, typ string) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Type == typ
+}
+
+func (s *Service) decoder() *types.Decoder {
+	return &types.Decoder{Strict: s.Strict}
 }
 
 func (s *Service) Do(action string, body interface{}, a interface{}) error {
+	return s.DoContext(context.Background(), action, body, a)
+}
+
+// DoContext performs the given action, aborting it when ctx is done.
+func (s *Service) DoContext(ctx context.Context, action string, body interface{}, a interface{}) error {
+	return Chain(s.do, s.Middleware...)(ctx, action, body, a)
+}
+
+func (s *Service) do(ctx context.Context, action string, body interface{}, a interface{}) error {
 	url := fmt.Sprintf("https://dynamodb.%s.amazonaws.com/", s.Region)
 
 	b, err := json.Marshal(body)
@@ -54,7 +145,7 @@ func (s *Service) Do(action string, body interface{}, a interface{}) error {
 		return err
 	}
 
-	r, err := http.NewRequest("POST", url, bytes.NewBuffer(b))
+	r, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(b))
 	if err != nil {
 		return err
 	}
@@ -71,10 +162,18 @@ func (s *Service) Do(action string, body interface{}, a interface{}) error {
 	if status := resp.StatusCode; status != 200 {
 		var e struct {
 			Message string
-			Type    string
+			Type    string `json:"__type"`
 		}
 		json.NewDecoder(resp.Body).Decode(&e)
-		return errors.New(fmt.Sprintf("%s: %s", e.Type, e.Message))
+		// Types are qualified, as in "com.amazonaws.dynamodb.v20120810#ResourceNotFoundException"
+		if i := strings.LastIndex(e.Type, "#"); i >= 0 {
+			e.Type = e.Type[i+1:]
+		}
+		return &Error{
+			StatusCode: status,
+			Type:       e.Type,
+			Message:    e.Message,
+		}
 	}
 
 	if a == nil {
@@ -84,31 +183,55 @@ func (s *Service) Do(action string, body interface{}, a interface{}) error {
 }
 
 // Get the corresponding item from the given table.
-func (s *Service) Get(tableName string, item interface{}) error {
-	keys, err := types.Marshal(item, true)
+func (s *Service) Get(tableName string, item interface{}, opts ...Option) error {
+	_, err := s.get(tableName, item, item, opts)
+	return err
+}
+
+// Get the item corresponding to the keys of key into item, reporting
+// whether it exists.
+func (s *Service) get(tableName string, key, item interface{}, opts []Option) (bool, error) {
+	o := newOptions(opts)
+	if o.err != nil {
+		return false, o.err
+	}
+	keys, err := types.Marshal(key, true)
 	if err != nil {
-		return err
+		return false, err
 	}
 	var resp struct {
-		Item types.AttributeValue
+		Item             types.AttributeValue
+		ConsumedCapacity *types.ConsumedCapacity
 	}
 	body := struct {
-		TableName string
-		Key       types.AttributeValue
+		TableName                string
+		Key                      types.AttributeValue
+		ConsistentRead           bool              `json:",omitempty"`
+		ReturnConsumedCapacity   string            `json:",omitempty"`
+		ProjectionExpression     string            `json:",omitempty"`
+		ExpressionAttributeNames map[string]string `json:",omitempty"`
 	}{
-		TableName: tableName,
-		Key:       keys,
+		TableName:                tableName,
+		Key:                      keys,
+		ConsistentRead:           o.consistent,
+		ReturnConsumedCapacity:   o.capacity,
+		ProjectionExpression:     o.projection,
+		ExpressionAttributeNames: referenced(o.names, o.projection),
 	}
 	err = s.Do("GetItem", body, &resp)
 	if err != nil {
-		return err
+		return false, err
 	}
-	return types.Unmarshal(resp.Item, item)
+	o.report(resp.ConsumedCapacity)
+	if resp.Item == nil {
+		return false, nil
+	}
+	return true, s.decoder().Unmarshal(resp.Item, item)
 }
 
 // Get the corresponding item from the given table.
-func Get(tableName string, item interface{}) error {
-	return DefaultService.Get(tableName, item)
+func Get(tableName string, item interface{}, opts ...Option) error {
+	return DefaultService.Get(tableName, item, opts...)
 }
 
 // Create or replace the item in the given table.
@@ -209,30 +332,74 @@ func BatchDelete(tableName string, items interface{}) error {
 	return DefaultService.BatchDelete(tableName, items)
 }
 
-// Return all items in the given table.
-func (s *Service) All(tableName string, item interface{}) (interface{}, error) {
-	var items []types.AttributeValue
-	var resp struct {
-		Items            []types.AttributeValue
-		LastEvaluatedKey types.AttributeValue
+// Return the corresponding items from the given table.
+func (s *Service) BatchGet(tableName string, items interface{}, opts ...Option) (interface{}, error) {
+	o := newOptions(opts)
+	if o.err != nil {
+		return nil, o.err
 	}
-	for {
-		body := struct {
-			TableName         string
-			ExclusiveStartKey types.AttributeValue
-		}{
-			TableName:         tableName,
-			ExclusiveStartKey: resp.LastEvaluatedKey,
-		}
-		if err := s.Do("Scan", body, &resp); err != nil {
+	rv := reflect.ValueOf(items)
+	var keys []types.AttributeValue
+	for i := 0; i < rv.Len(); i++ {
+		key, err := types.Marshal(rv.Index(i).Interface(), true)
+		if err != nil {
 			return nil, err
 		}
-		items = append(items, resp.Items...)
-		if resp.LastEvaluatedKey == nil {
-			break
+		keys = append(keys, key)
+	}
+	var values []types.AttributeValue
+	for len(keys) > 0 {
+		n := len(keys)
+		if n > maxBatchGet {
+			n = maxBatchGet
+		}
+		requests := types.ReadRequests{
+			tableName: &types.KeysAndAttributes{
+				Keys:                     keys[:n],
+				ConsistentRead:           o.consistent,
+				ProjectionExpression:     o.projection,
+				ExpressionAttributeNames: referenced(o.names, o.projection),
+			},
+		}
+		keys = keys[n:]
+		for len(requests) > 0 {
+			var resp struct {
+				Responses        map[string][]types.AttributeValue
+				UnprocessedKeys  types.ReadRequests
+				ConsumedCapacity []*types.ConsumedCapacity
+			}
+			body := struct {
+				RequestItems           types.ReadRequests
+				ReturnConsumedCapacity string `json:",omitempty"`
+			}{
+				RequestItems:           requests,
+				ReturnConsumedCapacity: o.capacity,
+			}
+			if err := s.Do("BatchGetItem", body, &resp); err != nil {
+				return nil, err
+			}
+			for _, c := range resp.ConsumedCapacity {
+				o.report(c)
+			}
+			values = append(values, resp.Responses[tableName]...)
+			requests = resp.UnprocessedKeys
 		}
 	}
-	return types.MakeSlice(items, item)
+	t := rv.Type().Elem()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return s.decoder().MakeSlice(values, reflect.New(t).Interface())
+}
+
+// Return the corresponding items from the given table.
+func BatchGet(tableName string, items interface{}, opts ...Option) (interface{}, error) {
+	return DefaultService.BatchGet(tableName, items, opts...)
+}
+
+// Return all items in the given table.
+func (s *Service) All(tableName string, item interface{}) (interface{}, error) {
+	return collect(s.Scan(context.Background(), tableName, item), item)
 }
 
 // Return all items in the given table.
@@ -240,41 +407,274 @@ func All(tableName string, item interface{}) (interface{}, error) {
 	return DefaultService.All(tableName, item)
 }
 
-// Return given attributes for all item in the given table.
-func (s *Service) Pluck(tableName string, item interface{}, attrs ...string) (interface{}, error) {
-	var items []types.AttributeValue
-	var resp struct {
-		Items            []types.AttributeValue
-		LastEvaluatedKey types.AttributeValue
-	}
+// Iterate over all items in the given table, fetching pages as needed.
+func (s *Service) Scan(ctx context.Context, tableName string, item interface{}, opts ...Option) *Iterator {
+	o := newOptions(opts)
+	return s.newIterator(ctx, item, o, s.scan(ctx, tableName, o))
+}
+
+// Iterate over all items in the given table, fetching pages as needed.
+func Scan(ctx context.Context, tableName string, item interface{}, opts ...Option) *Iterator {
+	return DefaultService.Scan(ctx, tableName, item, opts...)
+}
+
+// Count items in the given table, and items evaluated before any filter
+// was applied, without returning them.
+func (s *Service) Count(ctx context.Context, tableName string, opts ...Option) (count, scanned int, err error) {
+	o := newOptions(opts)
+	o.selection = SelectCount
+	fetch := s.scan(ctx, tableName, o)
+	key := o.startKey
 	for {
+		p, err := fetch(key)
+		if err != nil {
+			return 0, 0, err
+		}
+		count += p.Count
+		scanned += p.ScannedCount
+		if key = p.LastEvaluatedKey; len(key) == 0 {
+			return count, scanned, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return 0, 0, err
+		}
+	}
+}
+
+// Count items in the given table, and items evaluated before any filter
+// was applied, without returning them.
+func Count(ctx context.Context, tableName string, opts ...Option) (count, scanned int, err error) {
+	return DefaultService.Count(ctx, tableName, opts...)
+}
+
+func (s *Service) scan(ctx context.Context, tableName string, o *options) fetchFunc {
+	return func(startKey types.AttributeValue) (*page, error) {
+		if o.err != nil {
+			return nil, o.err
+		}
+		var resp page
 		body := struct {
-			TableName         string
-			ExclusiveStartKey types.AttributeValue
-			AttributesToGet   []string
+			TableName                 string
+			IndexName                 string `json:",omitempty"`
+			ExclusiveStartKey         types.AttributeValue
+			Limit                     int                  `json:",omitempty"`
+			Select                    string               `json:",omitempty"`
+			ConsistentRead            bool                 `json:",omitempty"`
+			ReturnConsumedCapacity    string               `json:",omitempty"`
+			FilterExpression          string               `json:",omitempty"`
+			ProjectionExpression      string               `json:",omitempty"`
+			ExpressionAttributeNames  map[string]string    `json:",omitempty"`
+			ExpressionAttributeValues types.AttributeValue `json:",omitempty"`
+			Segment                   *int                 `json:",omitempty"`
+			TotalSegments             int                  `json:",omitempty"`
 		}{
-			TableName:         tableName,
-			ExclusiveStartKey: resp.LastEvaluatedKey,
-			AttributesToGet:   attrs,
+			TableName:                 tableName,
+			IndexName:                 o.index,
+			ExclusiveStartKey:         startKey,
+			Limit:                     o.limit,
+			Select:                    o.selection,
+			ConsistentRead:            o.consistent,
+			ReturnConsumedCapacity:    o.capacity,
+			FilterExpression:          o.filter,
+			ProjectionExpression:      o.projection,
+			ExpressionAttributeNames:  o.names,
+			ExpressionAttributeValues: o.values,
+			Segment:                   o.segmentValue(),
+			TotalSegments:             o.totalSegments,
 		}
-		if err := s.Do("Scan", body, &resp); err != nil {
+		if err := s.DoContext(ctx, "Scan", body, &resp); err != nil {
 			return nil, err
 		}
-		items = append(items, resp.Items...)
-		if resp.LastEvaluatedKey == nil {
-			break
-		}
+		o.report(resp.ConsumedCapacity)
+		return &resp, nil
 	}
-	return types.MakeSlice(items, item)
 }
 
-// Return given attributes for all item in the given table.
+// Return given attributes for all item in the given table. Attributes are
+// document paths as accepted by Project.
+func (s *Service) Pluck(tableName string, item interface{}, attrs ...string) (interface{}, error) {
+	return collect(s.Scan(context.Background(), tableName, item, Project(attrs...)), item)
+}
+
+// Return given attributes for all item in the given table. Attributes are
+// document paths as accepted by Project.
 func Pluck(tableName string, item interface{}, attrs ...string) (interface{}, error) {
 	return DefaultService.Pluck(tableName, item, attrs...)
 }
 
+// Return all items sharing the hash key of the given item, and its range
+// key when set.
+func (s *Service) Query(tableName string, item interface{}, opts ...Option) (interface{}, error) {
+	ctx := context.Background()
+	o := newOptions(opts)
+	fetch, err := s.query(ctx, tableName, item, o)
+	if err != nil {
+		return nil, err
+	}
+	return collect(s.newIterator(ctx, item, o, fetch), item)
+}
+
+// Return all items sharing the hash key of the given item, and its range
+// key when set.
+func Query(tableName string, item interface{}, opts ...Option) (interface{}, error) {
+	return DefaultService.Query(tableName, item, opts...)
+}
+
+func (s *Service) query(ctx context.Context, tableName string, item interface{}, o *options) (fetchFunc, error) {
+	if o.err != nil {
+		return nil, o.err
+	}
+	condition, names, values, err := keyCondition(item, o.index)
+	if err != nil {
+		return nil, err
+	}
+	for name := range names {
+		if _, present := o.names[name]; present {
+			return nil, fmt.Errorf("dynamodb: placeholder %s is reserved for the key condition", name)
+		}
+	}
+	for value := range values {
+		if _, present := o.values[value]; present {
+			return nil, fmt.Errorf("dynamodb: placeholder %s is reserved for the key condition", value)
+		}
+	}
+	return func(startKey types.AttributeValue) (*page, error) {
+		var resp page
+		body := struct {
+			TableName                 string
+			IndexName                 string `json:",omitempty"`
+			KeyConditionExpression    string
+			FilterExpression          string `json:",omitempty"`
+			ProjectionExpression      string `json:",omitempty"`
+			ExpressionAttributeNames  map[string]string
+			ExpressionAttributeValues types.AttributeValue
+			ExclusiveStartKey         types.AttributeValue
+			Limit                     int    `json:",omitempty"`
+			Select                    string `json:",omitempty"`
+			ConsistentRead            bool   `json:",omitempty"`
+			ReturnConsumedCapacity    string `json:",omitempty"`
+		}{
+			TableName:                 tableName,
+			IndexName:                 o.index,
+			KeyConditionExpression:    condition,
+			FilterExpression:          o.filter,
+			ProjectionExpression:      o.projection,
+			ExpressionAttributeNames:  merge(names, o.names),
+			ExpressionAttributeValues: mergeValues(values, o.values),
+			ExclusiveStartKey:         startKey,
+			Limit:                     o.limit,
+			Select:                    o.selection,
+			ConsistentRead:            o.consistent,
+			ReturnConsumedCapacity:    o.capacity,
+		}
+		if err := s.DoContext(ctx, "Query", body, &resp); err != nil {
+			return nil, err
+		}
+		o.report(resp.ConsumedCapacity)
+		return &resp, nil
+	}, nil
+}
+
+// keyCondition builds a key condition expression matching the keys set
+// in the given item, either the keys of the table or of the given index,
+// using the #k0 and :k0 placeholders, and so on.
+func keyCondition(item interface{}, index string) (string, map[string]string, types.AttributeValue, error) {
+	var schema types.KeySchema
+	var err error
+	if index == "" {
+		schema, err = types.Keys(item)
+	} else {
+		schema, err = types.IndexKeys(item, index)
+	}
+	if err != nil {
+		return "", nil, nil, err
+	}
+	keys, err := types.MarshalKeys(item, schema)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	var conditions []string
+	names := make(map[string]string)
+	values := make(types.AttributeValue)
+	for i, key := range schema {
+		value, present := keys[key.AttributeName]
+		if !present {
+			if key.KeyType == "HASH" {
+				return "", nil, nil, ErrHashKey
+			}
+			continue
+		}
+		name, placeholder := fmt.Sprintf("#k%d", i), fmt.Sprintf(":k%d", i)
+		names[name] = key.AttributeName
+		values[placeholder] = value
+		conditions = append(conditions, fmt.Sprintf("%s = %s", name, placeholder))
+	}
+	if len(conditions) == 0 {
+		return "", nil, nil, ErrHashKey
+	}
+	return strings.Join(conditions, " AND "), names, values, nil
+}
+
+// Values accepted by TableOptions.BillingMode.
+const (
+	BillingProvisioned   = "PROVISIONED"
+	BillingPayPerRequest = "PAY_PER_REQUEST"
+)
+
+// Values accepted by TableOptions.TableClass.
+const (
+	TableClassStandard         = "STANDARD"
+	TableClassInfrequentAccess = "STANDARD_INFREQUENT_ACCESS"
+)
+
+// Values accepted by types.StreamSpecification.StreamViewType.
+const (
+	StreamKeysOnly        = "KEYS_ONLY"
+	StreamNewImage        = "NEW_IMAGE"
+	StreamOldImage        = "OLD_IMAGE"
+	StreamNewAndOldImages = "NEW_AND_OLD_IMAGES"
+)
+
+// TableOptions configures the creation of a table.
+type TableOptions struct {
+	// Either BillingProvisioned, the default, or BillingPayPerRequest.
+	BillingMode string
+
+	// Throughput of the table and its global secondary indexes, required
+	// by provisioned tables.
+	ProvisionedThroughput *types.ProvisionedThroughput
+
+	SSESpecification *types.SSESpecification
+	Tags             []types.Tag
+
+	// Settings left unchanged by EnsureTable when nil or empty.
+	StreamSpecification       *types.StreamSpecification
+	TableClass                string
+	DeletionProtectionEnabled *bool
+}
+
 // Creates table corresponding to the given item.
 func (s *Service) CreateTable(tableName string, item interface{}, read, write int) error {
+	return s.CreateTableWithOptions(tableName, item, &TableOptions{
+		ProvisionedThroughput: &types.ProvisionedThroughput{
+			ReadCapacityUnits:  read,
+			WriteCapacityUnits: write,
+		},
+	})
+}
+
+// Creates table corresponding to the given item.
+func CreateTable(tableName string, item interface{}, read, write int) error {
+	return DefaultService.CreateTable(tableName, item, read, write)
+}
+
+// Creates table corresponding to the given item, configured with the
+// given options, nil being the default ones. Time to live can only be
+// enabled once the table is active, which EnsureTable waits for.
+func (s *Service) CreateTableWithOptions(tableName string, item interface{}, opts *TableOptions) error {
+	if opts == nil {
+		opts = &TableOptions{}
+	}
 	definitions, err := types.Definitions(item)
 	if err != nil {
 		return err
@@ -283,38 +683,124 @@ func (s *Service) CreateTable(tableName string, item interface{}, read, write in
 	if err != nil {
 		return err
 	}
+	global, local, err := types.Indexes(item)
+	if err != nil {
+		return err
+	}
+	if opts.BillingMode != BillingPayPerRequest {
+		if opts.ProvisionedThroughput == nil {
+			return ErrThroughput
+		}
+		for i := range global {
+			global[i].ProvisionedThroughput = opts.ProvisionedThroughput
+		}
+	}
 	body := struct {
-		TableName             string
-		ProvisionedThroughput types.ProvisionedThroughput
-		AttributeDefinitions  types.AttributeDefinitions
-		KeySchema             types.KeySchema
+		TableName                 string
+		BillingMode               string                       `json:",omitempty"`
+		ProvisionedThroughput     *types.ProvisionedThroughput `json:",omitempty"`
+		AttributeDefinitions      types.AttributeDefinitions
+		KeySchema                 types.KeySchema
+		GlobalSecondaryIndexes    []types.GlobalSecondaryIndex `json:",omitempty"`
+		LocalSecondaryIndexes     []types.LocalSecondaryIndex  `json:",omitempty"`
+		SSESpecification          *types.SSESpecification      `json:",omitempty"`
+		StreamSpecification       *types.StreamSpecification   `json:",omitempty"`
+		TableClass                string                       `json:",omitempty"`
+		Tags                      []types.Tag                  `json:",omitempty"`
+		DeletionProtectionEnabled *bool                        `json:",omitempty"`
 	}{
-		TableName: tableName,
-		ProvisionedThroughput: types.ProvisionedThroughput{
-			ReadCapacityUnits:  read,
-			WriteCapacityUnits: write,
-		},
-		AttributeDefinitions: definitions,
-		KeySchema:            keys,
+		TableName:                 tableName,
+		BillingMode:               opts.BillingMode,
+		AttributeDefinitions:      definitions,
+		KeySchema:                 keys,
+		GlobalSecondaryIndexes:    global,
+		LocalSecondaryIndexes:     local,
+		SSESpecification:          opts.SSESpecification,
+		StreamSpecification:       opts.StreamSpecification,
+		TableClass:                opts.TableClass,
+		Tags:                      opts.Tags,
+		DeletionProtectionEnabled: opts.DeletionProtectionEnabled,
+	}
+	if opts.BillingMode != BillingPayPerRequest {
+		body.ProvisionedThroughput = opts.ProvisionedThroughput
 	}
 	return s.Do("CreateTable", body, nil)
 }
 
-// Creates table corresponding to the given item.
-func CreateTable(tableName string, item interface{}, read, write int) error {
-	return DefaultService.CreateTable(tableName, item, read, write)
+// Creates table corresponding to the given item, configured with the
+// given options.
+func CreateTableWithOptions(tableName string, item interface{}, opts *TableOptions) error {
+	return DefaultService.CreateTableWithOptions(tableName, item, opts)
+}
+
+// UpdateOptions describes changes to a table, nil and empty fields being
+// left unchanged.
+type UpdateOptions struct {
+	// Either BillingProvisioned or BillingPayPerRequest.
+	BillingMode string
+
+	// Throughput of the table, required when switching to provisioned.
+	ProvisionedThroughput *types.ProvisionedThroughput
+
+	// Definitions of the key attributes of created indexes.
+	AttributeDefinitions types.AttributeDefinitions
+
+	// Indexes to create, update or delete. DynamoDB only accepts one
+	// creation or deletion per update.
+	GlobalSecondaryIndexUpdates []types.GlobalSecondaryIndexUpdate
+
+	StreamSpecification       *types.StreamSpecification
+	TableClass                string
+	DeletionProtectionEnabled *bool
+}
+
+// Updates the given table with the given options, nil changing nothing.
+func (s *Service) UpdateTable(tableName string, opts *UpdateOptions) error {
+	if opts == nil {
+		opts = &UpdateOptions{}
+	}
+	body := struct {
+		TableName                   string
+		BillingMode                 string                             `json:",omitempty"`
+		ProvisionedThroughput       *types.ProvisionedThroughput       `json:",omitempty"`
+		AttributeDefinitions        types.AttributeDefinitions         `json:",omitempty"`
+		GlobalSecondaryIndexUpdates []types.GlobalSecondaryIndexUpdate `json:",omitempty"`
+		StreamSpecification         *types.StreamSpecification         `json:",omitempty"`
+		TableClass                  string                             `json:",omitempty"`
+		DeletionProtectionEnabled   *bool                              `json:",omitempty"`
+	}{
+		TableName:                   tableName,
+		BillingMode:                 opts.BillingMode,
+		ProvisionedThroughput:       opts.ProvisionedThroughput,
+		AttributeDefinitions:        opts.AttributeDefinitions,
+		GlobalSecondaryIndexUpdates: opts.GlobalSecondaryIndexUpdates,
+		StreamSpecification:         opts.StreamSpecification,
+		TableClass:                  opts.TableClass,
+		DeletionProtectionEnabled:   opts.DeletionProtectionEnabled,
+	}
+	return s.Do("UpdateTable", body, nil)
+}
+
+// Updates the given table with the given options, nil changing nothing.
+func UpdateTable(tableName string, opts *UpdateOptions) error {
+	return DefaultService.UpdateTable(tableName, opts)
 }
 
 // List existing tables.
 func (s *Service) ListTables() ([]string, error) {
-	var resp struct {
-		TableNames []string
-	}
-	err := s.Do("ListTables", new(struct{}), &resp)
-	if err != nil {
-		return nil, err
+	var names []string
+	var start string
+	for {
+		page, next, err := s.ListTablesPage(0, start)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, page...)
+		if next == "" {
+			return names, nil
+		}
+		start = next
 	}
-	return resp.TableNames, nil
 }
 
 // List existing tables.
@@ -322,8 +808,79 @@ func ListTables() ([]string, error) {
 	return DefaultService.ListTables()
 }
 
+// List at most limit tables, or as many as DynamoDB returns when zero, after
+// the given table name, and the name to continue from, empty on the last page.
+func (s *Service) ListTablesPage(limit int, start string) ([]string, string, error) {
+	var resp struct {
+		TableNames             []string
+		LastEvaluatedTableName string
+	}
+	body := struct {
+		Limit                   int    `json:",omitempty"`
+		ExclusiveStartTableName string `json:",omitempty"`
+	}{
+		Limit:                   limit,
+		ExclusiveStartTableName: start,
+	}
+	err := s.Do("ListTables", body, &resp)
+	if err != nil {
+		return nil, "", err
+	}
+	return resp.TableNames, resp.LastEvaluatedTableName, nil
+}
+
+// List at most limit tables after the given table name, and the name to
+// continue from.
+func ListTablesPage(limit int, start string) ([]string, string, error) {
+	return DefaultService.ListTablesPage(limit, start)
+}
+
+// List existing tables whose name starts with the given prefix.
+func (s *Service) ListTablesWithPrefix(prefix string) ([]string, error) {
+	var names []string
+	var start string
+	for {
+		page, next, err := s.ListTablesPage(0, start)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range page {
+			if strings.HasPrefix(name, prefix) {
+				names = append(names, name)
+			} else if name > prefix {
+				// Tables are listed in order, none are left with the prefix
+				return names, nil
+			}
+		}
+		if next == "" {
+			return names, nil
+		}
+		start = next
+	}
+}
+
+// List existing tables whose name starts with the given prefix.
+func ListTablesWithPrefix(prefix string) ([]string, error) {
+	return DefaultService.ListTablesWithPrefix(prefix)
+}
+
+// Returns the table names matching the given expression.
+func MatchTables(names []string, re *regexp.Regexp) []string {
+	var matches []string
+	for _, name := range names {
+		if re.MatchString(name) {
+			matches = append(matches, name)
+		}
+	}
+	return matches
+}
+
 // Describe given table.
 func (s *Service) DescribeTable(tableName string) (types.Table, error) {
+	return s.describeTable(context.Background(), tableName)
+}
+
+func (s *Service) describeTable(ctx context.Context, tableName string) (types.Table, error) {
 	var resp struct {
 		Table types.Table
 	}
@@ -332,7 +889,7 @@ func (s *Service) DescribeTable(tableName string) (types.Table, error) {
 	}{
 		TableName: tableName,
 	}
-	err := s.Do("DescribeTable", body, &resp)
+	err := s.DoContext(ctx, "DescribeTable", body, &resp)
 	if err != nil {
 		return types.Table{}, err
 	}
@@ -358,3 +915,51 @@ func (s *Service) DeleteTable(tableName string) error {
 func DeleteTable(tableName string) error {
 	return DefaultService.DeleteTable(tableName)
 }
+
+// Enables or disables time to live on the given attribute of the given
+// table.
+func (s *Service) UpdateTimeToLive(tableName, attribute string, enabled bool) error {
+	body := struct {
+		TableName               string
+		TimeToLiveSpecification types.TimeToLiveSpecification
+	}{
+		TableName: tableName,
+		TimeToLiveSpecification: types.TimeToLiveSpecification{
+			AttributeName: attribute,
+			Enabled:       enabled,
+		},
+	}
+	return s.Do("UpdateTimeToLive", body, nil)
+}
+
+// Enables or disables time to live on the given attribute of the given
+// table.
+func UpdateTimeToLive(tableName, attribute string, enabled bool) error {
+	return DefaultService.UpdateTimeToLive(tableName, attribute, enabled)
+}
+
+// Describe time to live of the given table.
+func (s *Service) DescribeTimeToLive(tableName string) (types.TimeToLiveDescription, error) {
+	return s.describeTimeToLive(context.Background(), tableName)
+}
+
+func (s *Service) describeTimeToLive(ctx context.Context, tableName string) (types.TimeToLiveDescription, error) {
+	var resp struct {
+		TimeToLiveDescription types.TimeToLiveDescription
+	}
+	body := struct {
+		TableName string
+	}{
+		TableName: tableName,
+	}
+	err := s.DoContext(ctx, "DescribeTimeToLive", body, &resp)
+	if err != nil {
+		return types.TimeToLiveDescription{}, err
+	}
+	return resp.TimeToLiveDescription, nil
+}
+
+// Describe time to live of the given table.
+func DescribeTimeToLive(tableName string) (types.TimeToLiveDescription, error) {
+	return DefaultService.DescribeTimeToLive(tableName)
+}
@@ -1,9 +1,42 @@
 package dynamodb
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/cyberdelia/dynamodb/types"
+	"io"
+	"net/http"
+	"reflect"
+	"regexp"
 	"testing"
 )
 
+// Returns a service answering requests with the given function.
+func testService(fn func(action string, body map[string]interface{}) (int, interface{})) *Service {
+	return &Service{
+		Region:  "us-east-1",
+		Version: "20120810",
+		Client: DoerFunc(func(r *http.Request) (*http.Response, error) {
+			var body map[string]interface{}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				return nil, err
+			}
+			target := r.Header.Get("X-Amz-Target")
+			status, resp := fn(target[len("DynamoDB_20120810."):], body)
+			b, err := json.Marshal(resp)
+			if err != nil {
+				return nil, err
+			}
+			return &http.Response{
+				StatusCode: status,
+				Body:       io.NopCloser(bytes.NewReader(b)),
+				Request:    r,
+			}, nil
+		}),
+	}
+}
+
 type paper struct {
 	Title   string   `dynamo:"title,hash"`
 	Year    int      `dynamo:"year,range"`
@@ -139,6 +172,135 @@ func TestPluck(t *testing.T) {
 	}
 }
 
+func TestQuery(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	items, err := Query("papers", &paper{
+		Title: "Dynamo: Amazon’s Highly Available Key-value Store",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	papers := items.([]*paper)
+	if len(papers) < 1 {
+		t.Error("no items returned")
+	}
+	if papers[0].Year != 2007 {
+		t.Error("year don't match")
+	}
+}
+
+func TestKeyCondition(t *testing.T) {
+	condition, names, values, err := keyCondition(&paper{
+		Title: "Dynamo: Amazon’s Highly Available Key-value Store",
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if condition != "#k0 = :k0" {
+		t.Errorf("got %v wants %v", condition, "#k0 = :k0")
+	}
+	if !reflect.DeepEqual(names, map[string]string{"#k0": "title"}) {
+		t.Errorf("got %v", names)
+	}
+	if _, present := values[":k0"]; !present {
+		t.Errorf("got %v", values)
+	}
+	if _, _, _, err := keyCondition(&paper{}, ""); err != ErrHashKey {
+		t.Errorf("got %v wants %v", err, ErrHashKey)
+	}
+}
+
+func TestIndexKeyCondition(t *testing.T) {
+	type profile struct {
+		Name string
+	}
+	type user struct {
+		ID      string            `dynamo:"id,hash"`
+		Email   string            `dynamo:"email,gsi=ByEmail:hash"`
+		Profile profile           `dynamo:"profile"`
+		Tags    map[string]string `dynamo:"tags"`
+	}
+	item := &user{
+		Email:   "a@example.com",
+		Profile: profile{Name: "A"},
+		Tags:    map[string]string{"role": "admin"},
+	}
+	condition, names, values, err := keyCondition(item, "ByEmail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if condition != "#k0 = :k0" || names["#k0"] != "email" {
+		t.Errorf("got %v with %v", condition, names)
+	}
+	if len(values) != 1 || values[":k0"]["S"] != "a@example.com" {
+		t.Errorf("got %v", values)
+	}
+}
+
+func TestQueryReservedPlaceholders(t *testing.T) {
+	s := testService(func(action string, b map[string]interface{}) (int, interface{}) {
+		return 200, map[string]interface{}{}
+	})
+	item := &paper{Title: "Dynamo"}
+	filters := []Option{
+		Filter("#k0 > :y", map[string]string{"#k0": "year"}, types.AttributeValue{":y": {"N": "2000"}}),
+		Filter("#y > :k0", map[string]string{"#y": "year"}, types.AttributeValue{":k0": {"N": "2000"}}),
+	}
+	for _, filter := range filters {
+		if _, err := s.Query("papers", item, filter); err == nil {
+			t.Error("got no error wants an error for a reserved placeholder")
+		}
+	}
+	filter := Filter("#y > :y", map[string]string{"#y": "year"}, types.AttributeValue{":y": {"N": "2000"}})
+	if _, err := s.Query("papers", item, filter); err != nil {
+		t.Errorf("got %v wants %v", err, nil)
+	}
+}
+
+func TestBatchGet(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	items, err := BatchGet("papers", []*paper{
+		{
+			Title: "Dynamo: Amazon’s Highly Available Key-value Store",
+			Year:  2007,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items.([]*paper)) != 1 {
+		t.Error("item not returned")
+	}
+}
+
+func TestTable(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	papers := NewTable[paper](DefaultService, "papers")
+	item, err := papers.Get(paper{
+		Title: "Dynamo: Amazon’s Highly Available Key-value Store",
+		Year:  2007,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Score != 1.5 {
+		t.Error("score don't match")
+	}
+	items, err := papers.Scan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) < 1 {
+		t.Error("no items returned")
+	}
+}
+
 func TestDelete(t *testing.T) {
 	if testing.Short() {
 		t.Skip()
@@ -205,3 +367,213 @@ func TestBatchDelete(t *testing.T) {
 // 		t.Fatal(err)
 // 	}
 // }
+
+func TestCount(t *testing.T) {
+	var requests []map[string]interface{}
+	s := testService(func(action string, body map[string]interface{}) (int, interface{}) {
+		requests = append(requests, body)
+		if body["ExclusiveStartKey"] == nil {
+			return 200, map[string]interface{}{
+				"Count":            2,
+				"ScannedCount":     5,
+				"LastEvaluatedKey": map[string]interface{}{"title": map[string]string{"S": "a"}},
+			}
+		}
+		return 200, map[string]interface{}{
+			"Count":        1,
+			"ScannedCount": 3,
+		}
+	})
+	count, scanned, err := s.Count(context.Background(), "papers", Filter("#y > :y", map[string]string{
+		"#y": "year",
+	}, types.AttributeValue{
+		":y": {"N": "2000"},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 || scanned != 8 {
+		t.Errorf("got %d and %d wants 3 and 8", count, scanned)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests wants 2", len(requests))
+	}
+	body := requests[0]
+	if body["Select"] != "COUNT" || body["FilterExpression"] != "#y > :y" {
+		t.Errorf("got %v", body)
+	}
+	if _, present := body["Segment"]; present {
+		t.Errorf("got %v", body)
+	}
+}
+
+func TestConsumedCapacity(t *testing.T) {
+	var body map[string]interface{}
+	s := testService(func(action string, b map[string]interface{}) (int, interface{}) {
+		body = b
+		return 200, map[string]interface{}{
+			"Item": map[string]interface{}{
+				"title": map[string]string{"S": "a"},
+				"year":  map[string]string{"N": "2007"},
+				"score": map[string]string{"N": "1.5"},
+			},
+			"ConsumedCapacity": map[string]interface{}{
+				"TableName":     "papers",
+				"CapacityUnits": 1,
+			},
+		}
+	})
+	var consumed []*types.ConsumedCapacity
+	item := &paper{Title: "a", Year: 2007}
+	err := s.Get("papers", item, ConsistentRead(), ReturnConsumedCapacity(CapacityTotal, func(c *types.ConsumedCapacity) {
+		consumed = append(consumed, c)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body["ConsistentRead"] != true || body["ReturnConsumedCapacity"] != "TOTAL" {
+		t.Errorf("got %v", body)
+	}
+	if len(consumed) != 1 || consumed[0].CapacityUnits != 1 || consumed[0].TableName != "papers" {
+		t.Errorf("got %v", consumed)
+	}
+	if item.Score != 1.5 {
+		t.Errorf("got %v wants %v", item.Score, 1.5)
+	}
+}
+
+func TestCreateTableWithOptions(t *testing.T) {
+	var body map[string]interface{}
+	s := testService(func(action string, b map[string]interface{}) (int, interface{}) {
+		body = b
+		return 200, map[string]interface{}{}
+	})
+	type user struct {
+		ID    string `dynamo:"id,hash"`
+		Email string `dynamo:"email,gsi=ByEmail:hash"`
+	}
+	protected := true
+	err := s.CreateTableWithOptions("users", &user{}, &TableOptions{
+		BillingMode: BillingPayPerRequest,
+		StreamSpecification: &types.StreamSpecification{
+			StreamEnabled:  true,
+			StreamViewType: StreamNewAndOldImages,
+		},
+		Tags: []types.Tag{
+			{Key: "env", Value: "test"},
+		},
+		DeletionProtectionEnabled: &protected,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, present := body["ProvisionedThroughput"]; present {
+		t.Errorf("got %v", body)
+	}
+	if body["BillingMode"] != "PAY_PER_REQUEST" || body["DeletionProtectionEnabled"] != true {
+		t.Errorf("got %v", body)
+	}
+	index := body["GlobalSecondaryIndexes"].([]interface{})[0].(map[string]interface{})
+	if _, present := index["ProvisionedThroughput"]; present {
+		t.Errorf("got %v", index)
+	}
+	for _, opts := range []*TableOptions{{}, nil} {
+		if err := s.CreateTableWithOptions("users", &user{}, opts); err != ErrThroughput {
+			t.Errorf("got %v wants %v", err, ErrThroughput)
+		}
+	}
+}
+
+func TestUpdateTable(t *testing.T) {
+	var body map[string]interface{}
+	s := testService(func(action string, b map[string]interface{}) (int, interface{}) {
+		body = b
+		return 200, map[string]interface{}{}
+	})
+	protected := false
+	err := s.UpdateTable("papers", &UpdateOptions{
+		ProvisionedThroughput: &types.ProvisionedThroughput{
+			ReadCapacityUnits:  5,
+			WriteCapacityUnits: 5,
+		},
+		GlobalSecondaryIndexUpdates: []types.GlobalSecondaryIndexUpdate{
+			{Delete: &types.DeleteGlobalSecondaryIndexAction{IndexName: "ByYear"}},
+		},
+		DeletionProtectionEnabled: &protected,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, present := body["BillingMode"]; present {
+		t.Errorf("got %v", body)
+	}
+	if body["DeletionProtectionEnabled"] != false {
+		t.Errorf("got %v", body)
+	}
+	updates := []interface{}{
+		map[string]interface{}{
+			"Delete": map[string]interface{}{"IndexName": "ByYear"},
+		},
+	}
+	if !reflect.DeepEqual(body["GlobalSecondaryIndexUpdates"], updates) {
+		t.Errorf("got %v wants %v", body["GlobalSecondaryIndexUpdates"], updates)
+	}
+	if err := s.UpdateTable("papers", nil); err != nil {
+		t.Fatal(err)
+	}
+	if control := map[string]interface{}{"TableName": "papers"}; !reflect.DeepEqual(body, control) {
+		t.Errorf("got %v wants %v", body, control)
+	}
+}
+
+var tableNames = []string{"dev.papers", "dev.users", "prod.papers", "prod.users", "test.papers"}
+
+func tablesService() *Service {
+	return testService(func(action string, body map[string]interface{}) (int, interface{}) {
+		names := tableNames
+		if start, ok := body["ExclusiveStartTableName"].(string); ok {
+			for i, name := range names {
+				if name == start {
+					names = names[i+1:]
+					break
+				}
+			}
+		}
+		resp := map[string]interface{}{}
+		if len(names) > 2 {
+			names = names[:2]
+			resp["LastEvaluatedTableName"] = names[1]
+		}
+		resp["TableNames"] = names
+		return 200, resp
+	})
+}
+
+func TestListTablesPages(t *testing.T) {
+	names, err := tablesService().ListTables()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names, tableNames) {
+		t.Errorf("got %v wants %v", names, tableNames)
+	}
+}
+
+func TestListTablesWithPrefix(t *testing.T) {
+	names, err := tablesService().ListTablesWithPrefix("dev.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	control := []string{"dev.papers", "dev.users"}
+	if !reflect.DeepEqual(names, control) {
+		t.Errorf("got %v wants %v", names, control)
+	}
+}
+
+func TestMatchTables(t *testing.T) {
+	names := MatchTables(tableNames, regexp.MustCompile(`\.papers$`))
+	control := []string{"dev.papers", "prod.papers", "test.papers"}
+	if !reflect.DeepEqual(names, control) {
+		t.Errorf("got %v wants %v", names, control)
+	}
+}
@@ -0,0 +1,200 @@
+// OpenTelemetry instrumentation of DynamoDB actions
+//
+// The middleware records a span for each action performed by a service,
+// with the attributes of the database semantic conventions, and measures
+// their duration and consumed capacity. Capacity is only reported by
+// DynamoDB when requested, e.g. with dynamodb.ReturnConsumedCapacity.
+//
+//	s.Middleware = append([]dynamodb.Middleware{dynamodbotel.Middleware()}, s.Middleware...)
+//
+// Retries of the dynamodb.Retry middleware are counted when it follows
+// this one.
+package dynamodbotel
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/cyberdelia/dynamodb"
+	"github.com/cyberdelia/dynamodb/types"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"reflect"
+	"sort"
+	"time"
+)
+
+const name = "github.com/cyberdelia/dynamodb/dynamodbotel"
+
+// An Option configures the instrumentation.
+type Option func(*options)
+
+type options struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+	region         string
+}
+
+// Use the given tracer provider instead of the global one.
+func WithTracerProvider(p trace.TracerProvider) Option {
+	return func(o *options) {
+		o.tracerProvider = p
+	}
+}
+
+// Use the given meter provider instead of the global one.
+func WithMeterProvider(p metric.MeterProvider) Option {
+	return func(o *options) {
+		o.meterProvider = p
+	}
+}
+
+// Report the region of the service in the cloud.region attribute.
+func WithRegion(region string) Option {
+	return func(o *options) {
+		o.region = region
+	}
+}
+
+// Returns a middleware tracing and measuring actions.
+func Middleware(opts ...Option) dynamodb.Middleware {
+	o := &options{
+		tracerProvider: otel.GetTracerProvider(),
+		meterProvider:  otel.GetMeterProvider(),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	tracer := o.tracerProvider.Tracer(name)
+	meter := o.meterProvider.Meter(name)
+	duration, err := meter.Float64Histogram("db.client.operation.duration",
+		metric.WithDescription("Duration of DynamoDB actions."),
+		metric.WithUnit("s"))
+	if err != nil {
+		otel.Handle(err)
+	}
+	capacity, err := meter.Float64Histogram("aws.dynamodb.consumed_capacity",
+		metric.WithDescription("Capacity units consumed by DynamoDB actions."),
+		metric.WithUnit("{capacity_unit}"))
+	if err != nil {
+		otel.Handle(err)
+	}
+
+	return func(next dynamodb.Invoker) dynamodb.Invoker {
+		return func(ctx context.Context, action string, body interface{}, a interface{}) error {
+			attrs := []attribute.KeyValue{
+				attribute.String("db.system", "dynamodb"),
+				attribute.String("db.operation.name", action),
+				attribute.String("rpc.system", "aws-api"),
+				attribute.String("rpc.service", "DynamoDB"),
+				attribute.String("rpc.method", action),
+			}
+			if o.region != "" {
+				attrs = append(attrs, attribute.String("cloud.region", o.region))
+			}
+			tables := tableNames(body)
+			if len(tables) > 0 {
+				attrs = append(attrs, attribute.StringSlice("aws.dynamodb.table_names", tables))
+			}
+			if len(tables) == 1 {
+				attrs = append(attrs, attribute.String("db.collection.name", tables[0]))
+			}
+
+			ctx, span := tracer.Start(ctx, action, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+			defer span.End()
+			ctx = dynamodb.CountRetries(ctx)
+
+			start := time.Now()
+			err := next(ctx, action, body, a)
+			elapsed := time.Since(start)
+
+			retries := dynamodb.Retries(ctx)
+			span.SetAttributes(attribute.Int("aws.dynamodb.retry_count", retries))
+			consumed := consumedCapacity(a)
+			if len(consumed) > 0 {
+				var units float64
+				var descriptions []string
+				for _, c := range consumed {
+					units += c.CapacityUnits
+					if b, err := json.Marshal(c); err == nil {
+						descriptions = append(descriptions, string(b))
+					}
+				}
+				span.SetAttributes(attribute.StringSlice("aws.dynamodb.consumed_capacity", descriptions))
+				capacity.Record(ctx, units, metric.WithAttributes(attrs...))
+			}
+			if err != nil {
+				typ := "_OTHER"
+				var e *dynamodb.Error
+				if errors.As(err, &e) && e.Type != "" {
+					typ = e.Type
+				}
+				attrs = append(attrs, attribute.String("error.type", typ))
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			duration.Record(ctx, elapsed.Seconds(), metric.WithAttributes(attrs...))
+			return err
+		}
+	}
+}
+
+// Returns the value of the given field of a struct or map, or nil.
+func field(v interface{}, name string) interface{} {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	switch rv.Kind() {
+	case reflect.Struct:
+		if f := rv.FieldByName(name); f.IsValid() && f.CanInterface() {
+			return f.Interface()
+		}
+	case reflect.Map:
+		if rv.Type().Key().Kind() == reflect.String {
+			if f := rv.MapIndex(reflect.ValueOf(name).Convert(rv.Type().Key())); f.IsValid() {
+				return f.Interface()
+			}
+		}
+	}
+	return nil
+}
+
+// Returns the names of the tables of the given request body.
+func tableNames(body interface{}) []string {
+	if name, ok := field(body, "TableName").(string); ok && name != "" {
+		return []string{name}
+	}
+	// Batch requests hold items by table name.
+	items := reflect.ValueOf(field(body, "RequestItems"))
+	if items.Kind() != reflect.Map || items.Type().Key().Kind() != reflect.String {
+		return nil
+	}
+	var names []string
+	for _, k := range items.MapKeys() {
+		names = append(names, k.String())
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Returns the capacity consumed, as reported in the given response.
+func consumedCapacity(a interface{}) []*types.ConsumedCapacity {
+	switch c := field(a, "ConsumedCapacity").(type) {
+	case *types.ConsumedCapacity:
+		if c != nil {
+			return []*types.ConsumedCapacity{c}
+		}
+	case types.ConsumedCapacity:
+		return []*types.ConsumedCapacity{&c}
+	case []*types.ConsumedCapacity:
+		return c
+	case []types.ConsumedCapacity:
+		consumed := make([]*types.ConsumedCapacity, len(c))
+		for i := range c {
+			consumed[i] = &c[i]
+		}
+		return consumed
+	}
+	return nil
+}
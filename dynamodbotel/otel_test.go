@@ -0,0 +1,137 @@
+package dynamodbotel
+
+import (
+	"context"
+	"github.com/cyberdelia/dynamodb"
+	"github.com/cyberdelia/dynamodb/dynamodbtest"
+	"github.com/cyberdelia/dynamodb/types"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"testing"
+	"time"
+)
+
+type paper struct {
+	Title string `dynamo:"title,hash"`
+	Year  int    `dynamo:"year,range"`
+}
+
+func attributes(kvs []attribute.KeyValue) map[attribute.Key]attribute.Value {
+	m := make(map[attribute.Key]attribute.Value)
+	for _, kv := range kvs {
+		m[kv.Key] = kv.Value
+	}
+	return m
+}
+
+func TestMiddleware(t *testing.T) {
+	srv := dynamodbtest.NewServer()
+	defer srv.Close()
+	s := srv.Service()
+
+	spans := tracetest.NewSpanRecorder()
+	reader := sdkmetric.NewManualReader()
+	throttled := false
+	s.Middleware = []dynamodb.Middleware{
+		Middleware(
+			WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))),
+			WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+			WithRegion("us-east-1"),
+		),
+		dynamodb.Retry(1, time.Millisecond),
+		// Throttles the first PutItem.
+		func(next dynamodb.Invoker) dynamodb.Invoker {
+			return func(ctx context.Context, action string, body, a interface{}) error {
+				if action == "PutItem" && !throttled {
+					throttled = true
+					return &dynamodb.Error{StatusCode: 400, Type: "ProvisionedThroughputExceededException"}
+				}
+				return next(ctx, action, body, a)
+			}
+		},
+	}
+
+	if err := s.CreateTable("papers", &paper{}, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put("papers", &paper{Title: "Dynamo", Year: 2007}); err != nil {
+		t.Fatal(err)
+	}
+	p := &paper{Title: "Dynamo", Year: 2007}
+	if err := s.Get("papers", p, dynamodb.ReturnConsumedCapacity(dynamodb.CapacityTotal, func(*types.ConsumedCapacity) {})); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Get("books", p); !dynamodb.IsError(err, "ResourceNotFoundException") {
+		t.Fatalf("expected a missing table, got %v", err)
+	}
+
+	ended := spans.Ended()
+	if len(ended) != 4 {
+		t.Fatalf("expected 4 spans, got %d", len(ended))
+	}
+	for i, action := range []string{"CreateTable", "PutItem", "GetItem", "GetItem"} {
+		span := ended[i]
+		attrs := attributes(span.Attributes())
+		if span.Name() != action || attrs["db.system"].AsString() != "dynamodb" || attrs["db.operation.name"].AsString() != action {
+			t.Errorf("unexpected span %s with %v", span.Name(), attrs)
+		}
+	}
+	if attrs := attributes(ended[0].Attributes()); attrs["db.collection.name"].AsString() != "papers" || attrs["cloud.region"].AsString() != "us-east-1" {
+		t.Errorf("unexpected attributes %v", attrs)
+	}
+	if attrs := attributes(ended[1].Attributes()); attrs["aws.dynamodb.retry_count"].AsInt64() != 1 {
+		t.Errorf("expected a retry, got %v", attrs)
+	}
+	if attrs := attributes(ended[2].Attributes()); len(attrs["aws.dynamodb.consumed_capacity"].AsStringSlice()) != 1 {
+		t.Errorf("expected consumed capacity, got %v", attrs)
+	}
+	if status := ended[3].Status(); status.Code != codes.Error {
+		t.Errorf("expected an error status, got %v", status)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	histograms := make(map[string]metricdata.Histogram[float64])
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			histograms[m.Name] = m.Data.(metricdata.Histogram[float64])
+		}
+	}
+	var count, failures uint64
+	for _, point := range histograms["db.client.operation.duration"].DataPoints {
+		count += point.Count
+		if v, ok := point.Attributes.Value("error.type"); ok {
+			if v.AsString() != "ResourceNotFoundException" {
+				t.Errorf("unexpected error type %s", v.AsString())
+			}
+			failures += point.Count
+		}
+	}
+	if count != 4 || failures != 1 {
+		t.Errorf("expected 4 durations with 1 failure, got %d with %d", count, failures)
+	}
+	if points := histograms["aws.dynamodb.consumed_capacity"].DataPoints; len(points) != 1 || points[0].Sum != 1 {
+		t.Errorf("expected a capacity unit, got %+v", points)
+	}
+}
+
+func TestTableNames(t *testing.T) {
+	body := map[string]interface{}{
+		"RequestItems": map[string]interface{}{"papers": nil, "books": nil},
+	}
+	if names := tableNames(body); len(names) != 2 || names[0] != "books" || names[1] != "papers" {
+		t.Errorf("unexpected table names %v", names)
+	}
+	if names := tableNames(struct{ TableName string }{"papers"}); len(names) != 1 || names[0] != "papers" {
+		t.Errorf("unexpected table names %v", names)
+	}
+	if names := tableNames(nil); names != nil {
+		t.Errorf("unexpected table names %v", names)
+	}
+}
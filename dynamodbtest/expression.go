@@ -0,0 +1,1077 @@
+package dynamodbtest
+
+import (
+	"fmt"
+	"github.com/cyberdelia/dynamodb/types"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// A value is a single attribute value, as in {"S": "a"}.
+type value = map[string]interface{}
+
+// An element is either an attribute name or a list index of a document
+// path.
+type element struct {
+	name  string
+	index int
+}
+
+type path []element
+
+// A condition reports whether an item satisfies an expression.
+type condition func(item types.AttributeValue) bool
+
+// An operand evaluates to an attribute value of an item, or nil when the
+// item has no such attribute.
+type operand func(item types.AttributeValue) value
+
+const (
+	tokenEOF = iota
+	tokenName
+	tokenPlaceholder
+	tokenValue
+	tokenNumber
+	tokenSymbol
+)
+
+type token struct {
+	kind int
+	text string
+}
+
+func tokenize(expr string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '#' || c == ':' || isNameByte(c):
+			j := i + 1
+			for j < len(expr) && isNameByte(expr[j]) {
+				j++
+			}
+			kind := tokenName
+			switch {
+			case c == '#':
+				kind = tokenPlaceholder
+			case c == ':':
+				kind = tokenValue
+			case c >= '0' && c <= '9':
+				kind = tokenNumber
+			}
+			if j == i+1 && kind != tokenName && kind != tokenNumber {
+				return nil, fmt.Errorf("invalid token %q", expr[i:j])
+			}
+			tokens = append(tokens, token{kind, expr[i:j]})
+			i = j
+		case strings.HasPrefix(expr[i:], "<=") || strings.HasPrefix(expr[i:], ">=") || strings.HasPrefix(expr[i:], "<>"):
+			tokens = append(tokens, token{tokenSymbol, expr[i : i+2]})
+			i += 2
+		case strings.IndexByte("=<>(),.[]+-", c) >= 0:
+			tokens = append(tokens, token{tokenSymbol, expr[i : i+1]})
+			i++
+		default:
+			return nil, fmt.Errorf("invalid character %q", c)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF}), nil
+}
+
+func isNameByte(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// A parser parses condition, update and projection expressions, resolving
+// their placeholders.
+type parser struct {
+	tokens []token
+	pos    int
+	names  map[string]string
+	values types.AttributeValue
+	// Top-level attribute names referenced by the expression, and the
+	// ones modified by an update expression.
+	refs    map[string]bool
+	targets map[string]bool
+}
+
+func newParser(expr string, names map[string]string, values types.AttributeValue) (*parser, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	return &parser{
+		tokens:  tokens,
+		names:   names,
+		values:  values,
+		refs:    make(map[string]bool),
+		targets: make(map[string]bool),
+	}, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// keyword reports whether the next token is the given keyword, consuming
+// it if so.
+func (p *parser) keyword(k string) bool {
+	if t := p.peek(); t.kind == tokenName && strings.EqualFold(t.text, k) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// symbol reports whether the next token is the given symbol, consuming it
+// if so.
+func (p *parser) symbol(s string) bool {
+	if t := p.peek(); t.kind == tokenSymbol && t.text == s {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(s string) error {
+	if !p.symbol(s) {
+		return fmt.Errorf("expected %q, found %q", s, p.peek().text)
+	}
+	return nil
+}
+
+func (p *parser) end() error {
+	if t := p.peek(); t.kind != tokenEOF {
+		return fmt.Errorf("unexpected token %q", t.text)
+	}
+	return nil
+}
+
+func (p *parser) name() (string, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenName:
+		return t.text, nil
+	case tokenPlaceholder:
+		name, present := p.names[t.text]
+		if !present {
+			return "", fmt.Errorf("undefined attribute name %s", t.text)
+		}
+		return name, nil
+	}
+	return "", fmt.Errorf("expected an attribute name, found %q", t.text)
+}
+
+func (p *parser) path() (path, error) {
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	p.refs[name] = true
+	elements := path{{name: name}}
+	for {
+		switch {
+		case p.symbol("."):
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			elements = append(elements, element{name: name})
+		case p.symbol("["):
+			t := p.next()
+			if t.kind != tokenNumber {
+				return nil, fmt.Errorf("invalid list index %q", t.text)
+			}
+			index, err := strconv.Atoi(t.text)
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			elements = append(elements, element{index: index})
+		default:
+			return elements, nil
+		}
+	}
+}
+
+func (p *parser) value() (value, error) {
+	t := p.next()
+	if t.kind != tokenValue {
+		return nil, fmt.Errorf("expected an attribute value, found %q", t.text)
+	}
+	v, present := p.values[t.text]
+	if !present {
+		return nil, fmt.Errorf("undefined attribute value %s", t.text)
+	}
+	return v, nil
+}
+
+// Parses a condition expression.
+func parseCondition(expr string, names map[string]string, values types.AttributeValue) (condition, *parser, error) {
+	p, err := newParser(expr, names, values)
+	if err != nil {
+		return nil, nil, err
+	}
+	c, err := p.or()
+	if err != nil {
+		return nil, nil, err
+	}
+	return c, p, p.end()
+}
+
+func (p *parser) or() (condition, error) {
+	c, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("OR") {
+		left := c
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		c = func(item types.AttributeValue) bool {
+			return left(item) || right(item)
+		}
+	}
+	return c, nil
+}
+
+func (p *parser) and() (condition, error) {
+	c, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("AND") {
+		left := c
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		c = func(item types.AttributeValue) bool {
+			return left(item) && right(item)
+		}
+	}
+	return c, nil
+}
+
+func (p *parser) not() (condition, error) {
+	if p.keyword("NOT") {
+		c, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return func(item types.AttributeValue) bool {
+			return !c(item)
+		}, nil
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (condition, error) {
+	if p.symbol("(") {
+		c, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		return c, p.expect(")")
+	}
+	if t := p.peek(); t.kind == tokenName && p.tokens[p.pos+1].text == "(" && t.text != "size" {
+		return p.function()
+	}
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case p.keyword("BETWEEN"):
+		low, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		if !p.keyword("AND") {
+			return nil, fmt.Errorf("expected AND, found %q", p.peek().text)
+		}
+		high, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		return func(item types.AttributeValue) bool {
+			v := left(item)
+			return ordered(low(item), v, "<=") && ordered(v, high(item), "<=")
+		}, nil
+	case p.keyword("IN"):
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		var candidates []operand
+		for {
+			o, err := p.operand()
+			if err != nil {
+				return nil, err
+			}
+			candidates = append(candidates, o)
+			if !p.symbol(",") {
+				break
+			}
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return func(item types.AttributeValue) bool {
+			v := left(item)
+			for _, candidate := range candidates {
+				if equal(v, candidate(item)) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	}
+	t := p.next()
+	switch t.text {
+	case "=", "<>", "<", "<=", ">", ">=":
+	default:
+		return nil, fmt.Errorf("expected a comparator, found %q", t.text)
+	}
+	right, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	comparator := t.text
+	return func(item types.AttributeValue) bool {
+		a, b := left(item), right(item)
+		switch comparator {
+		case "=":
+			return a != nil && equal(a, b)
+		case "<>":
+			return a != nil && b != nil && !equal(a, b)
+		}
+		return ordered(a, b, comparator)
+	}, nil
+}
+
+func (p *parser) function() (condition, error) {
+	name := p.next().text
+	p.next()
+	target, err := p.path()
+	if err != nil {
+		return nil, err
+	}
+	var argument operand
+	switch name {
+	case "attribute_exists", "attribute_not_exists":
+	case "attribute_type", "begins_with", "contains":
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		if argument, err = p.operand(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("invalid function name %s", name)
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	switch name {
+	case "attribute_exists":
+		return func(item types.AttributeValue) bool {
+			return target.resolve(item) != nil
+		}, nil
+	case "attribute_not_exists":
+		return func(item types.AttributeValue) bool {
+			return target.resolve(item) == nil
+		}, nil
+	case "attribute_type":
+		return func(item types.AttributeValue) bool {
+			v, t := target.resolve(item), argument(item)
+			if v == nil || t == nil {
+				return false
+			}
+			k, _ := kind(v)
+			_, want := kind(t)
+			return k == want
+		}, nil
+	case "begins_with":
+		return func(item types.AttributeValue) bool {
+			v, prefix := target.resolve(item), argument(item)
+			k, s := kind(v)
+			pk, ps := kind(prefix)
+			if k != pk || (k != "S" && k != "B") {
+				return false
+			}
+			a, _ := s.(string)
+			b, _ := ps.(string)
+			return strings.HasPrefix(a, b)
+		}, nil
+	}
+	return func(item types.AttributeValue) bool {
+		v, needle := target.resolve(item), argument(item)
+		k, s := kind(v)
+		ok, os := kind(needle)
+		switch k {
+		case "S", "B":
+			a, _ := s.(string)
+			b, _ := os.(string)
+			return k == ok && strings.Contains(a, b)
+		case "SS", "NS", "BS":
+			if k != ok+"S" {
+				return false
+			}
+			for _, e := range members(s) {
+				if equal(value{ok: e}, needle) {
+					return true
+				}
+			}
+		case "L":
+			for _, e := range elements(s) {
+				if equal(e, needle) {
+					return true
+				}
+			}
+		}
+		return false
+	}, nil
+}
+
+func (p *parser) operand() (operand, error) {
+	t := p.peek()
+	switch {
+	case t.kind == tokenValue:
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		return func(types.AttributeValue) value {
+			return v
+		}, nil
+	case t.kind == tokenName && t.text == "size" && p.tokens[p.pos+1].text == "(":
+		p.pos += 2
+		target, err := p.path()
+		if err != nil {
+			return nil, err
+		}
+		return func(item types.AttributeValue) value {
+			v := target.resolve(item)
+			if v == nil {
+				return nil
+			}
+			var n int
+			switch k, e := kind(v); k {
+			case "S":
+				s, _ := e.(string)
+				n = utf8.RuneCountInString(s)
+			case "B":
+				s, _ := e.(string)
+				n = len(s)
+			case "SS", "NS", "BS":
+				n = len(members(e))
+			case "L":
+				n = len(elements(e))
+			case "M":
+				n = len(attributes(e))
+			default:
+				return nil
+			}
+			return value{"N": strconv.Itoa(n)}
+		}, p.expect(")")
+	}
+	target, err := p.path()
+	if err != nil {
+		return nil, err
+	}
+	return target.resolve, nil
+}
+
+// An update applies an update expression to an item.
+type update func(item, old types.AttributeValue) error
+
+// Parses an update expression.
+func parseUpdate(expr string, names map[string]string, values types.AttributeValue) (update, *parser, error) {
+	p, err := newParser(expr, names, values)
+	if err != nil {
+		return nil, nil, err
+	}
+	var updates []update
+	for p.peek().kind != tokenEOF {
+		var action func() (update, error)
+		switch {
+		case p.keyword("SET"):
+			action = p.set
+		case p.keyword("REMOVE"):
+			action = p.remove
+		case p.keyword("ADD"):
+			action = p.add
+		case p.keyword("DELETE"):
+			action = p.delete
+		default:
+			return nil, nil, fmt.Errorf("unexpected token %q", p.peek().text)
+		}
+		for {
+			u, err := action()
+			if err != nil {
+				return nil, nil, err
+			}
+			updates = append(updates, u)
+			if !p.symbol(",") {
+				break
+			}
+		}
+	}
+	if len(updates) == 0 {
+		return nil, nil, fmt.Errorf("empty update expression")
+	}
+	return func(item, old types.AttributeValue) error {
+		for _, u := range updates {
+			if err := u(item, old); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, p, nil
+}
+
+func (p *parser) set() (update, error) {
+	target, err := p.path()
+	if err != nil {
+		return nil, err
+	}
+	p.targets[target[0].name] = true
+	if err := p.expect("="); err != nil {
+		return nil, err
+	}
+	v, err := p.arithmetic()
+	if err != nil {
+		return nil, err
+	}
+	return func(item, old types.AttributeValue) error {
+		value, err := v(old)
+		if err != nil {
+			return err
+		}
+		return target.set(item, value)
+	}, nil
+}
+
+// A setter evaluates the value of a SET action against the item before
+// the update.
+type setter func(item types.AttributeValue) (value, error)
+
+func (p *parser) arithmetic() (setter, error) {
+	left, err := p.setOperand()
+	if err != nil {
+		return nil, err
+	}
+	var sign int
+	switch {
+	case p.symbol("+"):
+		sign = 1
+	case p.symbol("-"):
+		sign = -1
+	default:
+		return left, nil
+	}
+	right, err := p.setOperand()
+	if err != nil {
+		return nil, err
+	}
+	return func(item types.AttributeValue) (value, error) {
+		a, err := left(item)
+		if err != nil {
+			return nil, err
+		}
+		b, err := right(item)
+		if err != nil {
+			return nil, err
+		}
+		x, ok := number(a)
+		y, ok2 := number(b)
+		if !ok || !ok2 {
+			return nil, fmt.Errorf("incorrect operand type for operator or function")
+		}
+		if sign < 0 {
+			y.Neg(y)
+		}
+		return value{"N": formatNumber(x.Add(x, y))}, nil
+	}, nil
+}
+
+func (p *parser) setOperand() (setter, error) {
+	t := p.peek()
+	if t.kind == tokenName && p.tokens[p.pos+1].text == "(" {
+		p.pos += 2
+		switch t.text {
+		case "if_not_exists":
+			target, err := p.path()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+			fallback, err := p.setOperand()
+			if err != nil {
+				return nil, err
+			}
+			return func(item types.AttributeValue) (value, error) {
+				if v := target.resolve(item); v != nil {
+					return v, nil
+				}
+				return fallback(item)
+			}, p.expect(")")
+		case "list_append":
+			left, err := p.setOperand()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+			right, err := p.setOperand()
+			if err != nil {
+				return nil, err
+			}
+			return func(item types.AttributeValue) (value, error) {
+				a, err := left(item)
+				if err != nil {
+					return nil, err
+				}
+				b, err := right(item)
+				if err != nil {
+					return nil, err
+				}
+				ka, la := kind(a)
+				kb, lb := kind(b)
+				if ka != "L" || kb != "L" {
+					return nil, fmt.Errorf("incorrect operand type for operator or function")
+				}
+				list := append(append([]interface{}{}, la.([]interface{})...), lb.([]interface{})...)
+				return value{"L": list}, nil
+			}, p.expect(")")
+		}
+		return nil, fmt.Errorf("invalid function name %s", t.text)
+	}
+	o, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	return func(item types.AttributeValue) (value, error) {
+		v := o(item)
+		if v == nil {
+			return nil, fmt.Errorf("the provided expression refers to an attribute that does not exist in the item")
+		}
+		return v, nil
+	}, nil
+}
+
+func (p *parser) remove() (update, error) {
+	target, err := p.path()
+	if err != nil {
+		return nil, err
+	}
+	p.targets[target[0].name] = true
+	return func(item, old types.AttributeValue) error {
+		target.remove(item)
+		return nil
+	}, nil
+}
+
+func (p *parser) add() (update, error) {
+	target, err := p.path()
+	if err != nil {
+		return nil, err
+	}
+	p.targets[target[0].name] = true
+	v, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	return func(item, old types.AttributeValue) error {
+		current := target.resolve(item)
+		if current == nil {
+			return target.set(item, v)
+		}
+		k, e := kind(current)
+		vk, ve := kind(v)
+		switch {
+		case k == "N" && vk == "N":
+			x, _ := number(current)
+			y, _ := number(v)
+			return target.set(item, value{"N": formatNumber(x.Add(x, y))})
+		case k == vk && (k == "SS" || k == "NS" || k == "BS"):
+			set := members(e)
+			for _, m := range members(ve) {
+				if !contains(k, set, m) {
+					set = append(set, m)
+				}
+			}
+			return target.set(item, value{k: toInterfaces(set)})
+		}
+		return fmt.Errorf("incorrect operand type for operator or function")
+	}, nil
+}
+
+func (p *parser) delete() (update, error) {
+	target, err := p.path()
+	if err != nil {
+		return nil, err
+	}
+	p.targets[target[0].name] = true
+	v, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	return func(item, old types.AttributeValue) error {
+		current := target.resolve(item)
+		if current == nil {
+			return nil
+		}
+		k, e := kind(current)
+		vk, ve := kind(v)
+		if k != vk || (k != "SS" && k != "NS" && k != "BS") {
+			return fmt.Errorf("incorrect operand type for operator or function")
+		}
+		removed := members(ve)
+		var set []string
+		for _, m := range members(e) {
+			if !contains(k, removed, m) {
+				set = append(set, m)
+			}
+		}
+		if len(set) == 0 {
+			target.remove(item)
+			return nil
+		}
+		return target.set(item, value{k: toInterfaces(set)})
+	}, nil
+}
+
+// Parses a projection expression.
+func parseProjection(expr string, names map[string]string) ([]path, error) {
+	p, err := newParser(expr, names, nil)
+	if err != nil {
+		return nil, err
+	}
+	var paths []path
+	for {
+		target, err := p.path()
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, target)
+		if !p.symbol(",") {
+			break
+		}
+	}
+	return paths, p.end()
+}
+
+// project returns the attributes of the item at the given paths.
+func project(item types.AttributeValue, paths []path) types.AttributeValue {
+	projected := make(types.AttributeValue)
+	for _, target := range paths {
+		if v := target.resolve(item); v != nil {
+			name := target[0].name
+			projected[name] = merge(projected[name], target[1:], v)
+		}
+	}
+	return projected
+}
+
+// merge returns the given document with the value inserted at the given
+// path, creating the enclosing documents.
+func merge(doc value, p path, v value) value {
+	if len(p) == 0 {
+		return v
+	}
+	k, inner := kind(doc)
+	if p[0].name == "" {
+		// Selected elements of lists are compacted
+		var list []interface{}
+		if k == "L" {
+			list, _ = inner.([]interface{})
+		}
+		return value{"L": append(list, merge(nil, p[1:], v))}
+	}
+	m := make(map[string]interface{})
+	if k == "M" {
+		m, _ = inner.(map[string]interface{})
+	}
+	child, _ := m[p[0].name].(map[string]interface{})
+	m[p[0].name] = merge(child, p[1:], v)
+	return value{"M": m}
+}
+
+// resolve returns the attribute value at the path, or nil.
+func (p path) resolve(item types.AttributeValue) value {
+	v, present := item[p[0].name]
+	if !present {
+		return nil
+	}
+	for _, e := range p[1:] {
+		k, inner := kind(v)
+		switch {
+		case e.name != "" && k == "M":
+			v = attributes(inner)[e.name]
+		case e.name == "" && k == "L":
+			list := elements(inner)
+			if e.index >= len(list) {
+				return nil
+			}
+			v = list[e.index]
+		default:
+			return nil
+		}
+		if v == nil {
+			return nil
+		}
+	}
+	return v
+}
+
+// set replaces the attribute value at the path, whose enclosing document
+// must exist.
+func (p path) set(item types.AttributeValue, v value) error {
+	if len(p) == 1 {
+		item[p[0].name] = v
+		return nil
+	}
+	parent := p[:len(p)-1].resolve(item)
+	last := p[len(p)-1]
+	k, inner := kind(parent)
+	switch {
+	case last.name != "" && k == "M":
+		m, _ := inner.(map[string]interface{})
+		if m == nil {
+			m = make(map[string]interface{})
+			parent["M"] = m
+		}
+		m[last.name] = v
+		return nil
+	case last.name == "" && k == "L":
+		list, _ := inner.([]interface{})
+		if last.index < len(list) {
+			list[last.index] = v
+		} else {
+			list = append(list, v)
+		}
+		parent["L"] = list
+		return nil
+	}
+	return fmt.Errorf("the document path provided in the update expression is invalid for update")
+}
+
+// remove removes the attribute value at the path, if any.
+func (p path) remove(item types.AttributeValue) {
+	if len(p) == 1 {
+		delete(item, p[0].name)
+		return
+	}
+	parent := p[:len(p)-1].resolve(item)
+	last := p[len(p)-1]
+	k, inner := kind(parent)
+	switch {
+	case last.name != "" && k == "M":
+		m, _ := inner.(map[string]interface{})
+		delete(m, last.name)
+	case last.name == "" && k == "L":
+		list, _ := inner.([]interface{})
+		if last.index < len(list) {
+			parent["L"] = append(list[:last.index:last.index], list[last.index+1:]...)
+		}
+	}
+}
+
+// kind returns the type and the content of a single attribute value.
+func kind(v value) (string, interface{}) {
+	for k, e := range v {
+		return k, e
+	}
+	return "", nil
+}
+
+// attributes returns the attributes of a map attribute value.
+func attributes(v interface{}) types.AttributeValue {
+	switch m := v.(type) {
+	case types.AttributeValue:
+		return m
+	case map[string]interface{}:
+		a := make(types.AttributeValue, len(m))
+		for name, e := range m {
+			switch e := e.(type) {
+			case map[string]interface{}:
+				a[name] = e
+			}
+		}
+		return a
+	}
+	return nil
+}
+
+// elements returns the attribute values of a list attribute value.
+func elements(v interface{}) []value {
+	list, _ := v.([]interface{})
+	values := make([]value, 0, len(list))
+	for _, e := range list {
+		if e, ok := e.(map[string]interface{}); ok {
+			values = append(values, e)
+		}
+	}
+	return values
+}
+
+// members returns the members of a set attribute value.
+func members(v interface{}) []string {
+	switch set := v.(type) {
+	case []string:
+		return set
+	case []interface{}:
+		values := make([]string, 0, len(set))
+		for _, e := range set {
+			if s, ok := e.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+func toInterfaces[T any](s []T) []interface{} {
+	list := make([]interface{}, len(s))
+	for i, e := range s {
+		list[i] = e
+	}
+	return list
+}
+
+func contains(k string, set []string, m string) bool {
+	for _, e := range set {
+		if equal(value{k[:1]: e}, value{k[:1]: m}) {
+			return true
+		}
+	}
+	return false
+}
+
+func number(v value) (*big.Rat, bool) {
+	k, e := kind(v)
+	s, _ := e.(string)
+	if k != "N" {
+		return nil, false
+	}
+	return new(big.Rat).SetString(s)
+}
+
+func formatNumber(r *big.Rat) string {
+	if r.IsInt() {
+		return r.Num().String()
+	}
+	return strings.TrimRight(r.FloatString(38), "0")
+}
+
+// equal reports whether two attribute values are equal, numbers being
+// compared by value and sets regardless of the order of their members.
+func equal(a, b value) bool {
+	ka, ea := kind(a)
+	kb, eb := kind(b)
+	if ka != kb || a == nil {
+		return false
+	}
+	switch ka {
+	case "N":
+		x, ok := number(a)
+		y, ok2 := number(b)
+		return ok && ok2 && x.Cmp(y) == 0
+	case "SS", "NS", "BS":
+		x, y := members(ea), members(eb)
+		if len(x) != len(y) {
+			return false
+		}
+		for _, m := range x {
+			if !contains(ka, y, m) {
+				return false
+			}
+		}
+		return true
+	case "L":
+		x, y := elements(ea), elements(eb)
+		if len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case "M":
+		x, y := attributes(ea), attributes(eb)
+		if len(x) != len(y) {
+			return false
+		}
+		for name, v := range x {
+			if !equal(v, y[name]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(ea, eb)
+}
+
+// compare orders two scalar attribute values of the same type.
+func compare(a, b value) (int, bool) {
+	ka, ea := kind(a)
+	kb, eb := kind(b)
+	if ka != kb {
+		return 0, false
+	}
+	switch ka {
+	case "N":
+		x, ok := number(a)
+		y, ok2 := number(b)
+		if !ok || !ok2 {
+			return 0, false
+		}
+		return x.Cmp(y), true
+	case "S", "B":
+		x, _ := ea.(string)
+		y, _ := eb.(string)
+		return strings.Compare(x, y), true
+	}
+	return 0, false
+}
+
+func ordered(a, b value, comparator string) bool {
+	c, ok := compare(a, b)
+	if !ok {
+		return false
+	}
+	switch comparator {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
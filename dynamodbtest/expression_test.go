@@ -0,0 +1,144 @@
+package dynamodbtest
+
+import (
+	"encoding/json"
+	"github.com/cyberdelia/dynamodb/types"
+	"reflect"
+	"testing"
+)
+
+const itemJSON = `{
+	"title": {"S": "Dynamo"},
+	"year": {"N": "2007"},
+	"authors": {"SS": ["DeCandia", "Vogels"]},
+	"venue": {"M": {"name": {"S": "SOSP"}, "city": {"S": "Stevenson"}}},
+	"reviews": {"L": [{"M": {"score": {"N": "4"}}}, {"M": {"score": {"N": "5"}}}]},
+	"open": {"BOOL": true}
+}`
+
+func testItem(t *testing.T) types.AttributeValue {
+	var item types.AttributeValue
+	if err := json.Unmarshal([]byte(itemJSON), &item); err != nil {
+		t.Fatal(err)
+	}
+	return item
+}
+
+var conditionTests = []struct {
+	expr     string
+	expected bool
+}{
+	{"title = :title", true},
+	{"title <> :title", false},
+	{"#y BETWEEN :low AND :high", true},
+	{"#y > :high OR title = :title", true},
+	{"NOT (#y > :low AND title = :title)", false},
+	{"#y IN (:low, :year)", true},
+	{"begins_with(title, :prefix)", true},
+	{"contains(authors, :author)", true},
+	{"contains(title, :prefix)", true},
+	{"attribute_exists(venue.city) AND attribute_not_exists(venue.country)", true},
+	{"venue.#n = :venue", true},
+	{"reviews[1].score > reviews[0].score", true},
+	{"reviews[2].score > :low", false},
+	{"size(authors) = :two AND size(reviews) = :two", true},
+	{"attribute_type(#open, :bool)", true},
+	{"#open = :true", true},
+}
+
+func TestConditions(t *testing.T) {
+	item := testItem(t)
+	names := map[string]string{"#y": "year", "#n": "name", "#open": "open"}
+	values := types.AttributeValue{
+		":title":  {"S": "Dynamo"},
+		":prefix": {"S": "Dyn"},
+		":author": {"S": "Vogels"},
+		":venue":  {"S": "SOSP"},
+		":low":    {"N": "2000"},
+		":high":   {"N": "2010"},
+		":year":   {"N": "2007.0"},
+		":two":    {"N": "2"},
+		":bool":   {"S": "BOOL"},
+		":true":   {"BOOL": true},
+	}
+	for _, tt := range conditionTests {
+		c, _, err := parseCondition(tt.expr, names, values)
+		if err != nil {
+			t.Errorf("%s: %v", tt.expr, err)
+			continue
+		}
+		if got := c(item); got != tt.expected {
+			t.Errorf("%s: expected %v, got %v", tt.expr, tt.expected, got)
+		}
+	}
+}
+
+func TestInvalidExpressions(t *testing.T) {
+	for _, expr := range []string{"title =", "title = :missing", "#missing = :a", "title = :a AND", "unknown(title)", "(title = :a"} {
+		if _, _, err := parseCondition(expr, nil, types.AttributeValue{":a": {"S": "a"}}); err == nil {
+			t.Errorf("%s: expected an error", expr)
+		}
+	}
+	for _, expr := range []string{"SET", "SET a", "SET a = b + ", "RENAME a"} {
+		if _, _, err := parseUpdate(expr, nil, nil); err == nil {
+			t.Errorf("%s: expected an error", expr)
+		}
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	item := testItem(t)
+	u, p, err := parseUpdate("SET venue.country = :country, reviews = list_append(reviews, :review), #y = #y - :one REMOVE venue.city, reviews[0] DELETE authors :author", map[string]string{"#y": "year"}, types.AttributeValue{
+		":country": {"S": "USA"},
+		":review":  {"L": []interface{}{map[string]interface{}{"N": "3"}}},
+		":one":     {"N": "0.5"},
+		":author":  {"SS": []string{"DeCandia"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(p.targets, map[string]bool{"venue": true, "reviews": true, "year": true, "authors": true}) {
+		t.Errorf("unexpected targets %v", p.targets)
+	}
+	updated := copyItem(item)
+	if err := u(updated, item); err != nil {
+		t.Fatal(err)
+	}
+	var expected types.AttributeValue
+	json.Unmarshal([]byte(`{
+		"title": {"S": "Dynamo"},
+		"year": {"N": "2006.5"},
+		"authors": {"SS": ["Vogels"]},
+		"venue": {"M": {"name": {"S": "SOSP"}, "country": {"S": "USA"}}},
+		"reviews": {"L": [{"M": {"score": {"N": "5"}}}, {"N": "3"}]},
+		"open": {"BOOL": true}
+	}`), &expected)
+	if !reflect.DeepEqual(updated, expected) {
+		t.Errorf("expected %v, got %v", expected, updated)
+	}
+
+	u, _, err = parseUpdate("SET missing.nested = :a", nil, types.AttributeValue{":a": {"S": "a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := u(copyItem(item), item); err == nil {
+		t.Error("expected an error setting a path without parent")
+	}
+}
+
+func TestProject(t *testing.T) {
+	item := testItem(t)
+	paths, err := parseProjection("title, venue.#n, reviews[1].score", map[string]string{"#n": "name"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected types.AttributeValue
+	json.Unmarshal([]byte(`{
+		"title": {"S": "Dynamo"},
+		"venue": {"M": {"name": {"S": "SOSP"}}},
+		"reviews": {"L": [{"M": {"score": {"N": "5"}}}]}
+	}`), &expected)
+	if projected := copyItem(project(item, paths)); !reflect.DeepEqual(projected, expected) {
+		t.Errorf("expected %v, got %v", expected, projected)
+	}
+}
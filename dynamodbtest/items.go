@@ -0,0 +1,719 @@
+package dynamodbtest
+
+import (
+	"encoding/json"
+	"github.com/cyberdelia/dynamodb/types"
+	"hash/fnv"
+	"sort"
+	"strings"
+)
+
+const (
+	maxBatchWrite = 25
+	maxBatchGet   = 100
+)
+
+// expression holds the placeholders shared by the expressions of a
+// request.
+type expression struct {
+	ExpressionAttributeNames  map[string]string
+	ExpressionAttributeValues types.AttributeValue
+}
+
+// condition parses the given condition expression, which is always
+// satisfied when empty.
+func (e *expression) condition(expr, parameter string) (condition, *parser, error) {
+	if expr == "" {
+		return func(types.AttributeValue) bool { return true }, nil, nil
+	}
+	c, p, err := parseCondition(expr, e.ExpressionAttributeNames, e.ExpressionAttributeValues)
+	if err != nil {
+		return nil, nil, validation("Invalid %s: %s", parameter, err)
+	}
+	return c, p, nil
+}
+
+// projection parses the given projection expression, nil when empty.
+func (e *expression) projection(expr string) ([]path, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	paths, err := parseProjection(expr, e.ExpressionAttributeNames)
+	if err != nil {
+		return nil, validation("Invalid ProjectionExpression: %s", err)
+	}
+	return paths, nil
+}
+
+// id encodes the given key attributes into an identifier of the item.
+func id(key types.AttributeValue) string {
+	names := make([]string, 0, len(key))
+	for name := range key {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		k, e := kind(key[name])
+		s, _ := e.(string)
+		if r, ok := number(key[name]); ok {
+			s = formatNumber(r)
+		}
+		b.WriteString(name + "\x00" + k + "\x00" + s + "\x00")
+	}
+	return b.String()
+}
+
+// key returns the attributes of the item that are part of the given key
+// schemas.
+func key(item types.AttributeValue, schemas ...types.KeySchema) types.AttributeValue {
+	k := make(types.AttributeValue)
+	for _, schema := range schemas {
+		for _, e := range schema {
+			if v, present := item[e.AttributeName]; present {
+				k[e.AttributeName] = v
+			}
+		}
+	}
+	return k
+}
+
+// validateKey checks the given key has exactly the attributes of the key
+// schema of the table.
+func (t *table) validateKey(k types.AttributeValue) error {
+	if len(k) != len(t.KeySchema) {
+		return validation("The provided key element does not match the schema")
+	}
+	for _, e := range t.KeySchema {
+		v, present := k[e.AttributeName]
+		if typ, _ := kind(v); !present || typ != t.definition(e.AttributeName) {
+			return validation("The provided key element does not match the schema")
+		}
+	}
+	return nil
+}
+
+// validateStartKey checks the given start key has exactly the attributes
+// of the key schemas of the index and the table.
+func (t *table) validateStartKey(k types.AttributeValue, schema types.KeySchema) error {
+	names := t.keyNames(schema)
+	if len(k) != len(names) {
+		return validation("The provided starting key is invalid: The provided key element does not match the schema")
+	}
+	for _, name := range names {
+		v, present := k[name]
+		if typ, _ := kind(v); !present || typ != t.definition(name) {
+			return validation("The provided starting key is invalid: The provided key element does not match the schema")
+		}
+	}
+	return nil
+}
+
+// validateItem checks the given item has the keys of the table, and that
+// its keys of indexes have the expected type.
+func (t *table) validateItem(item types.AttributeValue) error {
+	for _, e := range t.KeySchema {
+		if _, present := item[e.AttributeName]; !present {
+			return validation("One or more parameter values were invalid: Missing the key %s in the item", e.AttributeName)
+		}
+	}
+	schemas := []types.KeySchema{t.KeySchema}
+	for _, index := range t.GlobalSecondaryIndexes {
+		schemas = append(schemas, index.KeySchema)
+	}
+	for _, index := range t.LocalSecondaryIndexes {
+		schemas = append(schemas, index.KeySchema)
+	}
+	for name, v := range key(item, schemas...) {
+		typ, e := kind(v)
+		if want := t.definition(name); typ != want {
+			return validation("One or more parameter values were invalid: Type mismatch for key %s expected: %s actual: %s", name, want, typ)
+		}
+		if s, _ := e.(string); s == "" {
+			return validation("One or more parameter values are not valid. The AttributeValue for a key attribute cannot contain an empty string value. Key: %s", name)
+		}
+	}
+	return nil
+}
+
+// keyNames returns the names of the key attributes of the given index
+// followed by the ones of the table, which order items of the index.
+func (t *table) keyNames(schema types.KeySchema) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, e := range append(append(types.KeySchema(nil), schema...), t.KeySchema...) {
+		if !seen[e.AttributeName] {
+			seen[e.AttributeName] = true
+			names = append(names, e.AttributeName)
+		}
+	}
+	return names
+}
+
+// indexItems returns the items having the keys of the given index, in
+// order.
+func (t *table) indexItems(schema types.KeySchema) []types.AttributeValue {
+	var items []types.AttributeValue
+	for _, item := range t.items {
+		if len(key(item, schema)) == len(schema) {
+			items = append(items, item)
+		}
+	}
+	names := t.keyNames(schema)
+	sort.Slice(items, func(i, j int) bool {
+		return compareKeys(items[i], items[j], names) < 0
+	})
+	return items
+}
+
+func compareKeys(a, b types.AttributeValue, names []string) int {
+	for _, name := range names {
+		if c, _ := compare(a[name], b[name]); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// copyItem returns a deep copy of the given item.
+func copyItem(item types.AttributeValue) types.AttributeValue {
+	if item == nil {
+		return nil
+	}
+	b, _ := json.Marshal(item)
+	var c types.AttributeValue
+	json.Unmarshal(b, &c)
+	return c
+}
+
+// returnValues returns the attributes of the item to return, among the
+// ones before and after a change.
+func returnValues(mode string, before, after types.AttributeValue, updated map[string]bool) (types.AttributeValue, error) {
+	var item types.AttributeValue
+	switch mode {
+	case "", "NONE":
+		return nil, nil
+	case "ALL_OLD":
+		return before, nil
+	case "ALL_NEW":
+		return after, nil
+	case "UPDATED_OLD":
+		item = before
+	case "UPDATED_NEW":
+		item = after
+	default:
+		return nil, validation("Invalid ReturnValues: %s", mode)
+	}
+	values := make(types.AttributeValue)
+	for name := range updated {
+		if v, present := item[name]; present {
+			values[name] = v
+		}
+	}
+	return values, nil
+}
+
+func consumed(mode, tableName string, units float64) *types.ConsumedCapacity {
+	switch mode {
+	case "TOTAL":
+		return &types.ConsumedCapacity{TableName: tableName, CapacityUnits: units}
+	case "INDEXES":
+		return &types.ConsumedCapacity{
+			TableName:     tableName,
+			CapacityUnits: units,
+			Table:         &types.Capacity{CapacityUnits: units},
+		}
+	}
+	return nil
+}
+
+type itemResponse struct {
+	Attributes       types.AttributeValue    `json:",omitempty"`
+	ConsumedCapacity *types.ConsumedCapacity `json:",omitempty"`
+}
+
+func (s *Server) getItem(b []byte) (interface{}, error) {
+	var req struct {
+		expression
+		TableName              string
+		Key                    types.AttributeValue
+		ProjectionExpression   string
+		ConsistentRead         bool
+		ReturnConsumedCapacity string
+	}
+	if err := decode(b, &req); err != nil {
+		return nil, err
+	}
+	t, err := s.table(req.TableName)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.validateKey(req.Key); err != nil {
+		return nil, err
+	}
+	paths, err := req.projection(req.ProjectionExpression)
+	if err != nil {
+		return nil, err
+	}
+	item := t.items[id(req.Key)]
+	if item != nil && paths != nil {
+		item = project(item, paths)
+	}
+	return struct {
+		Item             types.AttributeValue    `json:",omitempty"`
+		ConsumedCapacity *types.ConsumedCapacity `json:",omitempty"`
+	}{
+		Item:             item,
+		ConsumedCapacity: consumed(req.ReturnConsumedCapacity, req.TableName, 1),
+	}, nil
+}
+
+func (s *Server) putItem(b []byte) (interface{}, error) {
+	var req struct {
+		expression
+		TableName              string
+		Item                   types.AttributeValue
+		ConditionExpression    string
+		ReturnValues           string
+		ReturnConsumedCapacity string
+	}
+	if err := decode(b, &req); err != nil {
+		return nil, err
+	}
+	t, err := s.table(req.TableName)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.validateItem(req.Item); err != nil {
+		return nil, err
+	}
+	c, _, err := req.condition(req.ConditionExpression, "ConditionExpression")
+	if err != nil {
+		return nil, err
+	}
+	if req.ReturnValues != "" && req.ReturnValues != "NONE" && req.ReturnValues != "ALL_OLD" {
+		return nil, validation("ReturnValues can only be ALL_OLD or NONE")
+	}
+	i := id(key(req.Item, t.KeySchema))
+	old := t.items[i]
+	if !c(old) {
+		return nil, errConditionalCheck
+	}
+	t.items[i] = req.Item
+	attributes, _ := returnValues(req.ReturnValues, old, req.Item, nil)
+	return itemResponse{
+		Attributes:       attributes,
+		ConsumedCapacity: consumed(req.ReturnConsumedCapacity, req.TableName, 1),
+	}, nil
+}
+
+func (s *Server) deleteItem(b []byte) (interface{}, error) {
+	var req struct {
+		expression
+		TableName              string
+		Key                    types.AttributeValue
+		ConditionExpression    string
+		ReturnValues           string
+		ReturnConsumedCapacity string
+	}
+	if err := decode(b, &req); err != nil {
+		return nil, err
+	}
+	t, err := s.table(req.TableName)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.validateKey(req.Key); err != nil {
+		return nil, err
+	}
+	c, _, err := req.condition(req.ConditionExpression, "ConditionExpression")
+	if err != nil {
+		return nil, err
+	}
+	if req.ReturnValues != "" && req.ReturnValues != "NONE" && req.ReturnValues != "ALL_OLD" {
+		return nil, validation("ReturnValues can only be ALL_OLD or NONE")
+	}
+	i := id(req.Key)
+	old := t.items[i]
+	if !c(old) {
+		return nil, errConditionalCheck
+	}
+	delete(t.items, i)
+	attributes, _ := returnValues(req.ReturnValues, old, nil, nil)
+	return itemResponse{
+		Attributes:       attributes,
+		ConsumedCapacity: consumed(req.ReturnConsumedCapacity, req.TableName, 1),
+	}, nil
+}
+
+func (s *Server) updateItem(b []byte) (interface{}, error) {
+	var req struct {
+		expression
+		TableName              string
+		Key                    types.AttributeValue
+		UpdateExpression       string
+		ConditionExpression    string
+		ReturnValues           string
+		ReturnConsumedCapacity string
+	}
+	if err := decode(b, &req); err != nil {
+		return nil, err
+	}
+	t, err := s.table(req.TableName)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.validateKey(req.Key); err != nil {
+		return nil, err
+	}
+	c, _, err := req.condition(req.ConditionExpression, "ConditionExpression")
+	if err != nil {
+		return nil, err
+	}
+	var u update
+	updated := make(map[string]bool)
+	if req.UpdateExpression != "" {
+		var p *parser
+		u, p, err = parseUpdate(req.UpdateExpression, req.ExpressionAttributeNames, req.ExpressionAttributeValues)
+		if err != nil {
+			return nil, validation("Invalid UpdateExpression: %s", err)
+		}
+		updated = p.targets
+	}
+	for _, e := range t.KeySchema {
+		if updated[e.AttributeName] {
+			return nil, validation("One or more parameter values were invalid: Cannot update attribute %s. This attribute is part of the key", e.AttributeName)
+		}
+	}
+
+	i := id(req.Key)
+	old := t.items[i]
+	if !c(old) {
+		return nil, errConditionalCheck
+	}
+	item := copyItem(old)
+	if item == nil {
+		item = copyItem(req.Key)
+	}
+	if u != nil {
+		if err := u(item, old); err != nil {
+			return nil, validation("Invalid UpdateExpression: %s", err)
+		}
+	}
+	if err := t.validateItem(item); err != nil {
+		return nil, err
+	}
+	t.items[i] = item
+	attributes, err := returnValues(req.ReturnValues, old, item, updated)
+	if err != nil {
+		return nil, err
+	}
+	return itemResponse{
+		Attributes:       attributes,
+		ConsumedCapacity: consumed(req.ReturnConsumedCapacity, req.TableName, 1),
+	}, nil
+}
+
+func (s *Server) batchWriteItem(b []byte) (interface{}, error) {
+	var req struct {
+		RequestItems           types.WriteRequests
+		ReturnConsumedCapacity string
+	}
+	if err := decode(b, &req); err != nil {
+		return nil, err
+	}
+	type write struct {
+		table *table
+		id    string
+		item  types.AttributeValue
+	}
+	var writes []write
+	seen := make(map[string]bool)
+	for name, requests := range req.RequestItems {
+		t, err := s.table(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range requests {
+			var w write
+			switch {
+			case r.PutRequest != nil && r.DeleteRequest == nil:
+				if err := t.validateItem(r.PutRequest.Item); err != nil {
+					return nil, err
+				}
+				w = write{t, id(key(r.PutRequest.Item, t.KeySchema)), r.PutRequest.Item}
+			case r.DeleteRequest != nil && r.PutRequest == nil:
+				if err := t.validateKey(r.DeleteRequest.Key); err != nil {
+					return nil, err
+				}
+				w = write{table: t, id: id(r.DeleteRequest.Key)}
+			default:
+				return nil, validation("Supplied AttributeValue has more than one datatypes set, must contain exactly one of the supported datatypes")
+			}
+			if seen[name+"\x00"+w.id] {
+				return nil, validation("Provided list of item keys contains duplicates")
+			}
+			seen[name+"\x00"+w.id] = true
+			writes = append(writes, w)
+		}
+	}
+	if len(writes) == 0 || len(writes) > maxBatchWrite {
+		return nil, validation("Too many or too few items requested for the BatchWriteItem call")
+	}
+	var capacity []*types.ConsumedCapacity
+	for _, w := range writes {
+		if w.item != nil {
+			w.table.items[w.id] = w.item
+		} else {
+			delete(w.table.items, w.id)
+		}
+		if c := consumed(req.ReturnConsumedCapacity, w.table.TableName, 1); c != nil {
+			capacity = append(capacity, c)
+		}
+	}
+	return struct {
+		UnprocessedItems types.WriteRequests
+		ConsumedCapacity []*types.ConsumedCapacity `json:",omitempty"`
+	}{
+		UnprocessedItems: types.WriteRequests{},
+		ConsumedCapacity: capacity,
+	}, nil
+}
+
+func (s *Server) batchGetItem(b []byte) (interface{}, error) {
+	var req struct {
+		RequestItems           types.ReadRequests
+		ReturnConsumedCapacity string
+	}
+	if err := decode(b, &req); err != nil {
+		return nil, err
+	}
+	n := 0
+	for _, r := range req.RequestItems {
+		n += len(r.Keys)
+	}
+	if n == 0 || n > maxBatchGet {
+		return nil, validation("Too many or too few items requested for the BatchGetItem call")
+	}
+	responses := make(map[string][]types.AttributeValue)
+	var capacity []*types.ConsumedCapacity
+	for name, r := range req.RequestItems {
+		t, err := s.table(name)
+		if err != nil {
+			return nil, err
+		}
+		e := expression{ExpressionAttributeNames: r.ExpressionAttributeNames}
+		paths, err := e.projection(r.ProjectionExpression)
+		if err != nil {
+			return nil, err
+		}
+		seen := make(map[string]bool)
+		items := []types.AttributeValue{}
+		for _, k := range r.Keys {
+			if err := t.validateKey(k); err != nil {
+				return nil, err
+			}
+			i := id(k)
+			if seen[i] {
+				return nil, validation("Provided list of item keys contains duplicates")
+			}
+			seen[i] = true
+			if item, present := t.items[i]; present {
+				if paths != nil {
+					item = project(item, paths)
+				}
+				items = append(items, item)
+			}
+		}
+		responses[name] = items
+		if c := consumed(req.ReturnConsumedCapacity, name, float64(len(r.Keys))); c != nil {
+			capacity = append(capacity, c)
+		}
+	}
+	return struct {
+		Responses        map[string][]types.AttributeValue
+		UnprocessedKeys  types.ReadRequests
+		ConsumedCapacity []*types.ConsumedCapacity `json:",omitempty"`
+	}{
+		Responses:        responses,
+		UnprocessedKeys:  types.ReadRequests{},
+		ConsumedCapacity: capacity,
+	}, nil
+}
+
+// A search is a Query or Scan request.
+type search struct {
+	expression
+	TableName              string
+	IndexName              string
+	KeyConditionExpression string
+	FilterExpression       string
+	ProjectionExpression   string
+	ExclusiveStartKey      types.AttributeValue
+	Limit                  int
+	Select                 string
+	ScanIndexForward       *bool
+	ConsistentRead         bool
+	Segment                *int
+	TotalSegments          int
+	ReturnConsumedCapacity string
+}
+
+func (s *Server) query(b []byte) (interface{}, error) {
+	var req search
+	if err := decode(b, &req); err != nil {
+		return nil, err
+	}
+	t, err := s.table(req.TableName)
+	if err != nil {
+		return nil, err
+	}
+	schema, _, err := t.index(req.IndexName)
+	if err != nil {
+		return nil, err
+	}
+	if req.KeyConditionExpression == "" {
+		return nil, validation("Either the KeyConditions or KeyConditionExpression parameter must be specified in the request.")
+	}
+	c, p, err := req.condition(req.KeyConditionExpression, "KeyConditionExpression")
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[string]bool)
+	for _, e := range schema {
+		keys[e.AttributeName] = true
+	}
+	for name := range p.refs {
+		if !keys[name] {
+			return nil, validation("Query key condition not supported: %s is not a key attribute", name)
+		}
+	}
+	if !p.refs[schema[0].AttributeName] {
+		return nil, validation("Query condition missed key schema element: %s", schema[0].AttributeName)
+	}
+	var items []types.AttributeValue
+	for _, item := range t.indexItems(schema) {
+		if c(item) {
+			items = append(items, item)
+		}
+	}
+	if req.ScanIndexForward != nil && !*req.ScanIndexForward {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+	return s.page(t, &req, items)
+}
+
+func (s *Server) scan(b []byte) (interface{}, error) {
+	var req search
+	if err := decode(b, &req); err != nil {
+		return nil, err
+	}
+	t, err := s.table(req.TableName)
+	if err != nil {
+		return nil, err
+	}
+	schema, _, err := t.index(req.IndexName)
+	if err != nil {
+		return nil, err
+	}
+	if (req.Segment == nil) != (req.TotalSegments == 0) || (req.Segment != nil && (*req.Segment < 0 || *req.Segment >= req.TotalSegments)) {
+		return nil, validation("Segment and TotalSegments must be given together, with 0 <= Segment < TotalSegments")
+	}
+	var items []types.AttributeValue
+	for _, item := range t.indexItems(schema) {
+		if req.Segment != nil {
+			h := fnv.New32a()
+			h.Write([]byte(id(key(item, schema[:1]))))
+			if int(h.Sum32()%uint32(req.TotalSegments)) != *req.Segment {
+				continue
+			}
+		}
+		items = append(items, item)
+	}
+	return s.page(t, &req, items)
+}
+
+// page returns a page of the given items, ordered by the index of the
+// request, filtered and projected.
+func (s *Server) page(t *table, req *search, items []types.AttributeValue) (interface{}, error) {
+	schema, projection, _ := t.index(req.IndexName)
+	filter, _, err := req.condition(req.FilterExpression, "FilterExpression")
+	if err != nil {
+		return nil, err
+	}
+	paths, err := req.projection(req.ProjectionExpression)
+	if err != nil {
+		return nil, err
+	}
+	switch req.Select {
+	case "", "ALL_ATTRIBUTES", "ALL_PROJECTED_ATTRIBUTES", "COUNT":
+	case "SPECIFIC_ATTRIBUTES":
+		if paths == nil {
+			return nil, validation("Select SPECIFIC_ATTRIBUTES requires a ProjectionExpression")
+		}
+	default:
+		return nil, validation("Invalid Select: %s", req.Select)
+	}
+	if req.Limit < 0 {
+		return nil, validation("Limit must be greater than or equal to 1")
+	}
+
+	names := t.keyNames(schema)
+	if req.ExclusiveStartKey != nil {
+		if err := t.validateStartKey(req.ExclusiveStartKey, schema); err != nil {
+			return nil, err
+		}
+		forward := req.ScanIndexForward == nil || *req.ScanIndexForward
+		start := 0
+		for start < len(items) {
+			c := compareKeys(items[start], req.ExclusiveStartKey, names)
+			if (forward && c > 0) || (!forward && c < 0) {
+				break
+			}
+			start++
+		}
+		items = items[start:]
+	}
+	limit := req.Limit
+	if s.PageSize > 0 && (limit == 0 || s.PageSize < limit) {
+		limit = s.PageSize
+	}
+	resp := struct {
+		Items            []types.AttributeValue `json:",omitempty"`
+		Count            int
+		ScannedCount     int
+		LastEvaluatedKey types.AttributeValue    `json:",omitempty"`
+		ConsumedCapacity *types.ConsumedCapacity `json:",omitempty"`
+	}{}
+	if limit > 0 && len(items) > limit {
+		items = items[:limit]
+		resp.LastEvaluatedKey = key(items[limit-1], schema, t.KeySchema)
+	}
+	resp.ScannedCount = len(items)
+	for _, item := range items {
+		if !filter(item) {
+			continue
+		}
+		resp.Count++
+		if req.Select == "COUNT" {
+			continue
+		}
+		if projection != nil && projection.ProjectionType != "ALL" {
+			projected := key(item, schema, t.KeySchema)
+			for _, name := range projection.NonKeyAttributes {
+				if v, present := item[name]; present {
+					projected[name] = v
+				}
+			}
+			item = projected
+		}
+		if paths != nil {
+			item = project(item, paths)
+		}
+		resp.Items = append(resp.Items, item)
+	}
+	resp.ConsumedCapacity = consumed(req.ReturnConsumedCapacity, req.TableName, float64(resp.ScannedCount))
+	return resp, nil
+}
@@ -0,0 +1,189 @@
+// An in-memory DynamoDB server for tests
+//
+// The server implements the JSON protocol of the item and table
+// operations used by the dynamodb package, validating keys against the
+// schema of tables and evaluating condition, filter, update and
+// projection expressions.
+//
+//	srv := dynamodbtest.NewServer()
+//	defer srv.Close()
+//	s := srv.Service()
+package dynamodbtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/cyberdelia/dynamodb"
+	"github.com/cyberdelia/dynamodb/credentials"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+type Server struct {
+	*httptest.Server
+
+	// Maximum number of items evaluated by a single Query or Scan
+	// request, unlimited when zero.
+	PageSize int
+
+	mu     sync.Mutex
+	tables map[string]*table
+}
+
+// An Error is returned to clients as a DynamoDB error of the given type.
+type Error struct {
+	Type    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Type, e.Message)
+}
+
+func validation(format string, a ...interface{}) error {
+	return &Error{
+		Type:    "ValidationException",
+		Message: fmt.Sprintf(format, a...),
+	}
+}
+
+var errConditionalCheck = &Error{
+	Type:    "ConditionalCheckFailedException",
+	Message: "The conditional request failed",
+}
+
+var handlers = map[string]func(s *Server, b []byte) (interface{}, error){
+	"BatchGetItem":       (*Server).batchGetItem,
+	"BatchWriteItem":     (*Server).batchWriteItem,
+	"CreateTable":        (*Server).createTable,
+	"DeleteItem":         (*Server).deleteItem,
+	"DeleteTable":        (*Server).deleteTable,
+	"DescribeTable":      (*Server).describeTable,
+	"DescribeTimeToLive": (*Server).describeTimeToLive,
+	"GetItem":            (*Server).getItem,
+	"ListTables":         (*Server).listTables,
+	"PutItem":            (*Server).putItem,
+	"Query":              (*Server).query,
+	"Scan":               (*Server).scan,
+	"UpdateItem":         (*Server).updateItem,
+	"UpdateTimeToLive":   (*Server).updateTimeToLive,
+}
+
+// Starts a new server without any table.
+func NewServer() *Server {
+	s := &Server{
+		tables: make(map[string]*table),
+	}
+	s.Server = httptest.NewServer(s)
+	return s
+}
+
+// Returns a service sending its requests to the server.
+func (s *Server) Service() *dynamodb.Service {
+	u, _ := url.Parse(s.URL)
+	return &dynamodb.Service{
+		Region:  "us-east-1",
+		Version: "20120810",
+		Client: &credentials.Signer{
+			Credentials: credentials.Static{
+				AccessKeyID:     "AKIDEXAMPLE",
+				SecretAccessKey: "secret",
+			},
+			Client: &http.Client{
+				Transport: &transport{
+					url:  u,
+					next: s.Client().Transport,
+				},
+			},
+		},
+	}
+}
+
+// A transport sends requests to the server, leaving their host, from
+// which the signature is derived, untouched.
+type transport struct {
+	url  *url.URL
+	next http.RoundTripper
+}
+
+func (t *transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.URL.Scheme = t.url.Scheme
+	r.URL.Host = t.url.Host
+	return t.next.RoundTrip(r)
+}
+
+// Returns a client answering requests with the given function, given the
+// action of their target, e.g. "Scan", and their decoded body.
+func Client(fn func(action string, body map[string]interface{}) (int, interface{})) dynamodb.Doer {
+	return dynamodb.DoerFunc(func(r *http.Request) (*http.Response, error) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			return nil, err
+		}
+		target := r.Header.Get("X-Amz-Target")
+		status, resp := fn(target[strings.IndexByte(target, '.')+1:], body)
+		b, err := json.Marshal(resp)
+		if err != nil {
+			return nil, err
+		}
+		return &http.Response{
+			StatusCode: status,
+			Body:       io.NopCloser(bytes.NewReader(b)),
+			Request:    r,
+		}, nil
+	})
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	_, action, _ := strings.Cut(r.Header.Get("X-Amz-Target"), ".")
+	handler, present := handlers[action]
+	if !present || r.Method != "POST" {
+		write(w, nil, &Error{
+			Type:    "UnknownOperationException",
+			Message: fmt.Sprintf("Unknown operation %q", action),
+		})
+		return
+	}
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		write(w, nil, err)
+		return
+	}
+	s.mu.Lock()
+	resp, err := handler(s, b)
+	s.mu.Unlock()
+	write(w, resp, err)
+}
+
+func write(w http.ResponseWriter, resp interface{}, err error) {
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	if err != nil {
+		e, ok := err.(*Error)
+		if !ok {
+			e = &Error{Type: "InternalServerError", Message: err.Error()}
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{
+			"__type":  "com.amazonaws.dynamodb.v20120810#" + e.Type,
+			"message": e.Message,
+		})
+		return
+	}
+	if resp == nil {
+		resp = struct{}{}
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func decode(b []byte, v interface{}) error {
+	if err := json.Unmarshal(b, v); err != nil {
+		return &Error{Type: "SerializationException", Message: err.Error()}
+	}
+	return nil
+}
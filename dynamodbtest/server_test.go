@@ -0,0 +1,337 @@
+package dynamodbtest
+
+import (
+	"context"
+	"github.com/cyberdelia/dynamodb"
+	"github.com/cyberdelia/dynamodb/types"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+type paper struct {
+	Title   string   `dynamo:"title,hash"`
+	Year    int      `dynamo:"year,range,gsi=ByVenue:range"`
+	Venue   string   `dynamo:"venue,gsi=ByVenue:hash"`
+	Authors []string `dynamo:"authors"`
+}
+
+var papers = []*paper{
+	{Title: "Dynamo", Year: 2007, Venue: "SOSP", Authors: []string{"DeCandia", "Vogels"}},
+	{Title: "Bigtable", Year: 2006, Venue: "OSDI", Authors: []string{"Chang", "Dean"}},
+	{Title: "MapReduce", Year: 2004, Venue: "OSDI", Authors: []string{"Dean", "Ghemawat"}},
+	{Title: "Spanner", Year: 2012, Venue: "OSDI"},
+	{Title: "Dynamo", Year: 2022, Venue: "ATC"},
+}
+
+func testServer(t *testing.T) (*Server, *dynamodb.Service) {
+	srv := NewServer()
+	t.Cleanup(srv.Close)
+	s := srv.Service()
+	if err := s.CreateTable("papers", &paper{}, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.BatchPut("papers", papers); err != nil {
+		t.Fatal(err)
+	}
+	return srv, s
+}
+
+func TestItems(t *testing.T) {
+	_, s := testServer(t)
+
+	p := &paper{Title: "Dynamo", Year: 2007}
+	if err := s.Get("papers", p); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(p, papers[0]) {
+		t.Errorf("expected %+v, got %+v", papers[0], p)
+	}
+
+	p = &paper{Title: "Bigtable", Year: 2006}
+	if err := s.Get("papers", p, dynamodb.Project("venue")); err != nil {
+		t.Fatal(err)
+	}
+	if p.Venue != "OSDI" || p.Authors != nil {
+		t.Errorf("unexpected projected item %+v", p)
+	}
+
+	if err := s.Delete("papers", &paper{Title: "Spanner", Year: 2012}); err != nil {
+		t.Fatal(err)
+	}
+	p = &paper{Title: "Spanner", Year: 2012}
+	if err := s.Get("papers", p); err != nil || p.Venue != "" {
+		t.Errorf("expected deleted item, got %+v (%v)", p, err)
+	}
+	papersTable := dynamodb.NewTable[paper](s, "papers")
+	if _, err := papersTable.Get(paper{Title: "Spanner", Year: 2012}); err != dynamodb.ErrNotFound {
+		t.Errorf("expected a missing item, got %v", err)
+	}
+	if item, err := papersTable.Get(paper{Title: "Dynamo", Year: 2007, Authors: []string{"stale"}}); err != nil || !reflect.DeepEqual(&item, papers[0]) {
+		t.Errorf("expected %+v, got %+v (%v)", papers[0], item, err)
+	}
+	if item, err := papersTable.Get(paper{Title: "Spanner", Year: 2012, Venue: "stale"}); err != dynamodb.ErrNotFound || item.Venue != "" {
+		t.Errorf("expected a missing item, got %+v (%v)", item, err)
+	}
+
+	items, err := s.BatchGet("papers", []*paper{{Title: "Dynamo", Year: 2022}, {Title: "MapReduce", Year: 2004}, {Title: "Spanner", Year: 2012}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := items.([]*paper); len(got) != 2 {
+		t.Errorf("expected 2 items, got %d", len(got))
+	}
+
+	table, err := s.DescribeTable("papers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.ItemCount != 4 || table.GlobalSecondaryIndexes[0].ItemCount != 4 {
+		t.Errorf("unexpected item counts %d and %d", table.ItemCount, table.GlobalSecondaryIndexes[0].ItemCount)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	_, s := testServer(t)
+
+	items, err := s.Query("papers", &paper{Title: "Dynamo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := items.([]*paper); len(got) != 2 || got[0].Year != 2007 || got[1].Year != 2022 {
+		t.Errorf("unexpected items %+v", got)
+	}
+
+	items, err = s.Query("papers", &paper{Venue: "OSDI"}, dynamodb.Index("ByVenue"), dynamodb.Limit(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := items.([]*paper)
+	if len(got) != 3 || got[0].Title != "MapReduce" || got[2].Title != "Spanner" {
+		t.Errorf("unexpected items %+v", got)
+	}
+
+	items, err = s.Query("papers", &paper{Venue: "OSDI"}, dynamodb.Index("ByVenue"),
+		dynamodb.Filter("contains(#a, :a)", map[string]string{"#a": "authors"}, types.AttributeValue{":a": {"S": "Dean"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := items.([]*paper); len(got) != 2 {
+		t.Errorf("expected 2 items, got %+v", got)
+	}
+
+	err = s.Do("Query", map[string]interface{}{
+		"TableName":                 "papers",
+		"KeyConditionExpression":    "authors = :a",
+		"ExpressionAttributeValues": types.AttributeValue{":a": {"S": "Dean"}},
+	}, nil)
+	if !dynamodb.IsError(err, "ValidationException") {
+		t.Errorf("expected a validation error, got %v", err)
+	}
+}
+
+func TestScan(t *testing.T) {
+	srv, s := testServer(t)
+	srv.PageSize = 2
+
+	it := s.Scan(context.Background(), "papers", &paper{})
+	var titles []string
+	for it.Next() {
+		titles = append(titles, it.Item().(*paper).Title)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"Bigtable", "Dynamo", "Dynamo", "MapReduce", "Spanner"}
+	if !reflect.DeepEqual(titles, expected) {
+		t.Errorf("expected %v, got %v", expected, titles)
+	}
+
+	// Resuming mid-page from a projected item.
+	it = s.Scan(context.Background(), "papers", &paper{}, dynamodb.Project("venue"))
+	it.Next()
+	key := it.LastEvaluatedKey()
+	if len(key) != 2 {
+		t.Fatalf("expected the keys of the current item, got %v", key)
+	}
+	it = s.Scan(context.Background(), "papers", &paper{}, dynamodb.Project("venue"), dynamodb.StartKey(key))
+	n := 0
+	for it.Next() {
+		n++
+	}
+	if err := it.Err(); err != nil || n != len(papers)-1 {
+		t.Errorf("expected %d items, got %d (%v)", len(papers)-1, n, err)
+	}
+
+	count, scanned, err := s.Count(context.Background(), "papers",
+		dynamodb.Filter("#y > :y", map[string]string{"#y": "year"}, types.AttributeValue{":y": {"N": "2006"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 || scanned != 5 {
+		t.Errorf("expected 3 of 5 items, got %d of %d", count, scanned)
+	}
+
+	var mu sync.Mutex
+	n = 0
+	state := dynamodb.NewScanState(3)
+	err = s.ParallelScan(context.Background(), "papers", &paper{}, state, 3, func(segment int, it *dynamodb.Iterator) error {
+		for it.Next() {
+			mu.Lock()
+			n++
+			mu.Unlock()
+		}
+		return it.Err()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(papers) {
+		t.Errorf("expected %d items, got %d", len(papers), n)
+	}
+}
+
+func TestKeyValidation(t *testing.T) {
+	_, s := testServer(t)
+
+	err := s.Do("PutItem", map[string]interface{}{
+		"TableName": "papers",
+		"Item":      types.AttributeValue{"title": {"S": "Untitled"}},
+	}, nil)
+	if !dynamodb.IsError(err, "ValidationException") {
+		t.Errorf("expected a validation error for a missing key, got %v", err)
+	}
+	err = s.Do("GetItem", map[string]interface{}{
+		"TableName": "papers",
+		"Key":       types.AttributeValue{"title": {"S": "Dynamo"}, "year": {"S": "2007"}},
+	}, nil)
+	if !dynamodb.IsError(err, "ValidationException") {
+		t.Errorf("expected a validation error for a mistyped key, got %v", err)
+	}
+	dynamo := types.AttributeValue{"title": {"S": "Dynamo"}, "year": {"N": "2007"}}
+	for _, start := range []struct {
+		index string
+		key   types.AttributeValue
+		valid bool
+	}{
+		{"", types.AttributeValue{}, false},
+		{"", types.AttributeValue{"title": {"S": "Dynamo"}}, false},
+		{"", types.AttributeValue{"title": {"S": "Dynamo"}, "year": {"S": "2007"}}, false},
+		{"", dynamo, true},
+		{"ByVenue", dynamo, false},
+		{"ByVenue", types.AttributeValue{"title": {"S": "Dynamo"}, "year": {"N": "2007"}, "venue": {"S": "SOSP"}}, true},
+	} {
+		req := map[string]interface{}{
+			"TableName":         "papers",
+			"ExclusiveStartKey": start.key,
+		}
+		if start.index != "" {
+			req["IndexName"] = start.index
+		}
+		err := s.Do("Scan", req, nil)
+		if start.valid && err != nil {
+			t.Errorf("expected start key %v of %q to be accepted, got %v", start.key, start.index, err)
+		}
+		if !start.valid && !dynamodb.IsError(err, "ValidationException") {
+			t.Errorf("expected a validation error for start key %v of %q, got %v", start.key, start.index, err)
+		}
+	}
+	if err := s.Get("books", &paper{Title: "Dynamo", Year: 2007}); !dynamodb.IsError(err, "ResourceNotFoundException") {
+		t.Errorf("expected a missing table, got %v", err)
+	}
+	if err := s.CreateTable("papers", &paper{}, 1, 1); !dynamodb.IsError(err, "ResourceInUseException") {
+		t.Errorf("expected an existing table, got %v", err)
+	}
+}
+
+func TestUpdateItem(t *testing.T) {
+	_, s := testServer(t)
+
+	put := map[string]interface{}{
+		"TableName":           "papers",
+		"Item":                types.AttributeValue{"title": {"S": "Dynamo"}, "year": {"N": "2007"}},
+		"ConditionExpression": "attribute_not_exists(title)",
+	}
+	if err := s.Do("PutItem", put, nil); !dynamodb.IsError(err, "ConditionalCheckFailedException") {
+		t.Errorf("expected a failed condition, got %v", err)
+	}
+
+	update := func(expr, condition string, values types.AttributeValue) (types.AttributeValue, error) {
+		var resp struct {
+			Attributes types.AttributeValue
+		}
+		err := s.Do("UpdateItem", map[string]interface{}{
+			"TableName":                 "papers",
+			"Key":                       types.AttributeValue{"title": {"S": "Dynamo"}, "year": {"N": "2007"}},
+			"UpdateExpression":          expr,
+			"ConditionExpression":       condition,
+			"ExpressionAttributeNames":  map[string]string{"#c": "citations", "#a": "authors", "#v": "venue"},
+			"ExpressionAttributeValues": values,
+			"ReturnValues":              "ALL_NEW",
+		}, &resp)
+		return resp.Attributes, err
+	}
+	item, err := update("SET #c = if_not_exists(#c, :zero) + :one ADD #a :authors REMOVE #v", "#v = :venue", types.AttributeValue{
+		":zero":    {"N": "0"},
+		":one":     {"N": "1"},
+		":authors": {"SS": []string{"Vogels", "Hastorun"}},
+		":venue":   {"S": "SOSP"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equal(item["citations"], value{"N": "1"}) || !equal(item["authors"], value{"SS": []interface{}{"DeCandia", "Vogels", "Hastorun"}}) {
+		t.Errorf("unexpected item %v", item)
+	}
+	if _, present := item["venue"]; present {
+		t.Errorf("expected venue to be removed, got %v", item)
+	}
+
+	if _, err := update("SET #c = #c + :one", "attribute_exists(#v)", types.AttributeValue{":one": {"N": "1"}}); !dynamodb.IsError(err, "ConditionalCheckFailedException") {
+		t.Errorf("expected a failed condition, got %v", err)
+	}
+	if _, err := update("SET title = :t", "", types.AttributeValue{":t": {"S": "Dynamo"}}); !dynamodb.IsError(err, "ValidationException") {
+		t.Errorf("expected a validation error updating a key, got %v", err)
+	}
+}
+
+func TestTables(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	s := srv.Service()
+
+	for _, name := range []string{"c", "a", "b"} {
+		if err := s.CreateTableWithOptions(name, &paper{}, &dynamodb.TableOptions{BillingMode: dynamodb.BillingPayPerRequest}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	names, next, err := s.ListTablesPage(2, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names, []string{"a", "b"}) || next != "b" {
+		t.Errorf("unexpected page %v, %q", names, next)
+	}
+	names, err = s.ListTables()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names, []string{"a", "b", "c"}) {
+		t.Errorf("unexpected tables %v", names)
+	}
+
+	table, err := s.DescribeTable("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.TableStatus != "ACTIVE" || table.BillingModeSummary.BillingMode != dynamodb.BillingPayPerRequest || len(table.KeySchema) != 2 {
+		t.Errorf("unexpected table %+v", table)
+	}
+
+	if err := s.DeleteTable("a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.DescribeTable("a"); !dynamodb.IsError(err, "ResourceNotFoundException") {
+		t.Errorf("expected a deleted table, got %v", err)
+	}
+}
@@ -0,0 +1,310 @@
+package dynamodbtest
+
+import (
+	"fmt"
+	"github.com/cyberdelia/dynamodb/types"
+	"sort"
+	"time"
+)
+
+type table struct {
+	types.Table
+	items map[string]types.AttributeValue
+	ttl   types.TimeToLiveDescription
+}
+
+func (s *Server) table(name string) (*table, error) {
+	t, present := s.tables[name]
+	if !present {
+		return nil, &Error{
+			Type:    "ResourceNotFoundException",
+			Message: fmt.Sprintf("Requested resource not found: Table: %s not found", name),
+		}
+	}
+	return t, nil
+}
+
+// describe returns the description of the table, along with the number of
+// its items.
+func (t *table) describe() types.Table {
+	d := t.Table
+	d.ItemCount = len(t.items)
+	d.GlobalSecondaryIndexes = append([]types.GlobalSecondaryIndexDescription(nil), t.GlobalSecondaryIndexes...)
+	for i, index := range d.GlobalSecondaryIndexes {
+		d.GlobalSecondaryIndexes[i].ItemCount = len(t.indexItems(index.KeySchema))
+	}
+	d.LocalSecondaryIndexes = append([]types.LocalSecondaryIndexDescription(nil), t.LocalSecondaryIndexes...)
+	for i, index := range d.LocalSecondaryIndexes {
+		d.LocalSecondaryIndexes[i].ItemCount = len(t.indexItems(index.KeySchema))
+	}
+	return d
+}
+
+// index returns the key schema and projection of the given index, or of
+// the table when empty.
+func (t *table) index(name string) (types.KeySchema, *types.Projection, error) {
+	if name == "" {
+		return t.KeySchema, nil, nil
+	}
+	for _, index := range t.GlobalSecondaryIndexes {
+		if index.IndexName == name {
+			return index.KeySchema, &index.Projection, nil
+		}
+	}
+	for _, index := range t.LocalSecondaryIndexes {
+		if index.IndexName == name {
+			return index.KeySchema, &index.Projection, nil
+		}
+	}
+	return nil, nil, validation("The table does not have the specified index: %s", name)
+}
+
+// definition returns the type of the given attribute.
+func (t *table) definition(name string) string {
+	for _, d := range t.AttributeDefinitions {
+		if d.AttributeName == name {
+			return d.AttributeType
+		}
+	}
+	return ""
+}
+
+func (s *Server) createTable(b []byte) (interface{}, error) {
+	var req struct {
+		TableName                 string
+		AttributeDefinitions      types.AttributeDefinitions
+		KeySchema                 types.KeySchema
+		BillingMode               string
+		ProvisionedThroughput     *types.ProvisionedThroughput
+		GlobalSecondaryIndexes    []types.GlobalSecondaryIndex
+		LocalSecondaryIndexes     []types.LocalSecondaryIndex
+		StreamSpecification       *types.StreamSpecification
+		TableClass                string
+		DeletionProtectionEnabled bool
+	}
+	if err := decode(b, &req); err != nil {
+		return nil, err
+	}
+	if req.TableName == "" {
+		return nil, validation("TableName must not be empty")
+	}
+	if _, present := s.tables[req.TableName]; present {
+		return nil, &Error{
+			Type:    "ResourceInUseException",
+			Message: fmt.Sprintf("Table already exists: %s", req.TableName),
+		}
+	}
+	for _, d := range req.AttributeDefinitions {
+		switch d.AttributeType {
+		case "S", "N", "B":
+		default:
+			return nil, validation("Invalid type %q for attribute %s", d.AttributeType, d.AttributeName)
+		}
+	}
+	provisioned := req.BillingMode == "" || req.BillingMode == "PROVISIONED"
+	if provisioned && req.ProvisionedThroughput == nil {
+		return nil, validation("No provisioned throughput specified for the table")
+	}
+
+	now := types.Timestamp{Time: time.Now().UTC()}
+	arn := fmt.Sprintf("arn:aws:dynamodb:us-east-1:000000000000:table/%s", req.TableName)
+	t := &table{
+		Table: types.Table{
+			AttributeDefinitions:      req.AttributeDefinitions,
+			CreationDateTime:          now,
+			DeletionProtectionEnabled: req.DeletionProtectionEnabled,
+			KeySchema:                 req.KeySchema,
+			StreamSpecification:       req.StreamSpecification,
+			TableArn:                  arn,
+			TableName:                 req.TableName,
+			TableStatus:               "ACTIVE",
+		},
+		items: make(map[string]types.AttributeValue),
+		ttl: types.TimeToLiveDescription{
+			TimeToLiveStatus: "DISABLED",
+		},
+	}
+	if err := t.validateSchema(req.KeySchema); err != nil {
+		return nil, err
+	}
+	if provisioned {
+		t.ProvisionedThroughput.ReadCapacityUnits = req.ProvisionedThroughput.ReadCapacityUnits
+		t.ProvisionedThroughput.WriteCapacityUnits = req.ProvisionedThroughput.WriteCapacityUnits
+	} else {
+		t.BillingModeSummary = &types.BillingModeSummary{BillingMode: req.BillingMode}
+	}
+	if req.TableClass != "" {
+		t.TableClassSummary = &types.TableClassSummary{TableClass: req.TableClass}
+	}
+	if req.StreamSpecification != nil && req.StreamSpecification.StreamEnabled {
+		t.LatestStreamLabel = now.Format("2006-01-02T15:04:05.000")
+		t.LatestStreamArn = fmt.Sprintf("%s/stream/%s", arn, t.LatestStreamLabel)
+	}
+	names := make(map[string]bool)
+	for _, index := range req.GlobalSecondaryIndexes {
+		if err := t.validateIndex(names, index.IndexName, index.KeySchema); err != nil {
+			return nil, err
+		}
+		if provisioned && index.ProvisionedThroughput == nil {
+			return nil, validation("No provisioned throughput specified for the global secondary index %s", index.IndexName)
+		}
+		description := types.GlobalSecondaryIndexDescription{
+			IndexArn:    fmt.Sprintf("%s/index/%s", arn, index.IndexName),
+			IndexName:   index.IndexName,
+			IndexStatus: "ACTIVE",
+			KeySchema:   index.KeySchema,
+			Projection:  index.Projection,
+		}
+		if index.ProvisionedThroughput != nil {
+			description.ProvisionedThroughput.ReadCapacityUnits = index.ProvisionedThroughput.ReadCapacityUnits
+			description.ProvisionedThroughput.WriteCapacityUnits = index.ProvisionedThroughput.WriteCapacityUnits
+		}
+		t.GlobalSecondaryIndexes = append(t.GlobalSecondaryIndexes, description)
+	}
+	for _, index := range req.LocalSecondaryIndexes {
+		if err := t.validateIndex(names, index.IndexName, index.KeySchema); err != nil {
+			return nil, err
+		}
+		if len(index.KeySchema) != 2 || index.KeySchema[0].AttributeName != req.KeySchema[0].AttributeName {
+			return nil, validation("Local secondary index %s must have the same hash key as the table and a range key", index.IndexName)
+		}
+		t.LocalSecondaryIndexes = append(t.LocalSecondaryIndexes, types.LocalSecondaryIndexDescription{
+			IndexArn:   fmt.Sprintf("%s/index/%s", arn, index.IndexName),
+			IndexName:  index.IndexName,
+			KeySchema:  index.KeySchema,
+			Projection: index.Projection,
+		})
+	}
+	s.tables[req.TableName] = t
+	description := t.describe()
+	description.TableStatus = "CREATING"
+	return map[string]interface{}{"TableDescription": description}, nil
+}
+
+// validateSchema checks the given key schema has a hash key, optionally
+// followed by a range key, both being defined attributes.
+func (t *table) validateSchema(schema types.KeySchema) error {
+	if len(schema) == 0 || len(schema) > 2 || schema[0].KeyType != "HASH" || (len(schema) == 2 && schema[1].KeyType != "RANGE") {
+		return validation("Invalid KeySchema: expected a HASH key optionally followed by a RANGE key")
+	}
+	for _, key := range schema {
+		if t.definition(key.AttributeName) == "" {
+			return validation("One or more parameter values were invalid: Some index key attributes are not defined in AttributeDefinitions. Keys: [%s]", key.AttributeName)
+		}
+	}
+	return nil
+}
+
+func (t *table) validateIndex(names map[string]bool, name string, schema types.KeySchema) error {
+	if name == "" || names[name] {
+		return validation("Invalid or duplicate index name %q", name)
+	}
+	names[name] = true
+	return t.validateSchema(schema)
+}
+
+func (s *Server) describeTable(b []byte) (interface{}, error) {
+	var req struct {
+		TableName string
+	}
+	if err := decode(b, &req); err != nil {
+		return nil, err
+	}
+	t, err := s.table(req.TableName)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"Table": t.describe()}, nil
+}
+
+func (s *Server) listTables(b []byte) (interface{}, error) {
+	var req struct {
+		ExclusiveStartTableName string
+		Limit                   int
+	}
+	if err := decode(b, &req); err != nil {
+		return nil, err
+	}
+	if req.Limit == 0 {
+		req.Limit = 100
+	}
+	if req.Limit < 0 || req.Limit > 100 {
+		return nil, validation("Limit must be between 1 and 100")
+	}
+	names := make([]string, 0, len(s.tables))
+	for name := range s.tables {
+		if name > req.ExclusiveStartTableName {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	resp := struct {
+		TableNames             []string
+		LastEvaluatedTableName string `json:",omitempty"`
+	}{
+		TableNames: names,
+	}
+	if len(names) > req.Limit {
+		resp.TableNames = names[:req.Limit]
+		resp.LastEvaluatedTableName = names[req.Limit-1]
+	}
+	return resp, nil
+}
+
+func (s *Server) deleteTable(b []byte) (interface{}, error) {
+	var req struct {
+		TableName string
+	}
+	if err := decode(b, &req); err != nil {
+		return nil, err
+	}
+	t, err := s.table(req.TableName)
+	if err != nil {
+		return nil, err
+	}
+	if t.DeletionProtectionEnabled {
+		return nil, validation("Resource cannot be deleted as it is currently protected against deletion. Disable deletion protection first.")
+	}
+	delete(s.tables, req.TableName)
+	description := t.describe()
+	description.TableStatus = "DELETING"
+	return map[string]interface{}{"TableDescription": description}, nil
+}
+
+func (s *Server) updateTimeToLive(b []byte) (interface{}, error) {
+	var req struct {
+		TableName               string
+		TimeToLiveSpecification types.TimeToLiveSpecification
+	}
+	if err := decode(b, &req); err != nil {
+		return nil, err
+	}
+	t, err := s.table(req.TableName)
+	if err != nil {
+		return nil, err
+	}
+	spec := req.TimeToLiveSpecification
+	if spec.AttributeName == "" {
+		return nil, validation("TimeToLiveSpecification.AttributeName must not be empty")
+	}
+	if spec.Enabled {
+		t.ttl = types.TimeToLiveDescription{AttributeName: spec.AttributeName, TimeToLiveStatus: "ENABLED"}
+	} else {
+		t.ttl = types.TimeToLiveDescription{TimeToLiveStatus: "DISABLED"}
+	}
+	return map[string]interface{}{"TimeToLiveSpecification": spec}, nil
+}
+
+func (s *Server) describeTimeToLive(b []byte) (interface{}, error) {
+	var req struct {
+		TableName string
+	}
+	if err := decode(b, &req); err != nil {
+		return nil, err
+	}
+	t, err := s.table(req.TableName)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"TimeToLiveDescription": t.ttl}, nil
+}
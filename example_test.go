@@ -1,8 +1,11 @@
 package dynamodb_test
 
 import (
+	"context"
 	"fmt"
 	"github.com/cyberdelia/dynamodb"
+	"github.com/cyberdelia/dynamodb/types"
+	"time"
 )
 
 type Paper struct {
@@ -44,6 +47,57 @@ func ExampleAll() {
 	fmt.Println(papers)
 }
 
+func ExampleScan() {
+	it := dynamodb.Scan(context.Background(), "papers", &Paper{})
+	for it.Next() {
+		paper := it.Item().(*Paper)
+		fmt.Println(paper.Title)
+	}
+	if err := it.Err(); err != nil {
+		// Resume later using dynamodb.StartKey(it.LastEvaluatedKey())
+	}
+}
+
+func ExampleScanPage() {
+	s := &dynamodb.Service{
+		Region:       "us-east-1",
+		Version:      "20120810",
+		Client:       dynamodb.DefaultService.Client,
+		CursorSecret: []byte("secret"),
+	}
+	// The cursor typically comes from the query string of the request.
+	items, next, err := s.ScanPage(context.Background(), "papers", &Paper{}, "", dynamodb.Limit(25))
+	if err != nil {
+		// ...
+	}
+	fmt.Println(items.([]*Paper), next)
+}
+
+func ExampleParallelScan() {
+	state := dynamodb.NewScanState(8)
+	err := dynamodb.ParallelScan(context.Background(), "papers", &Paper{}, state, 4, func(segment int, it *dynamodb.Iterator) error {
+		for it.Next() {
+			fmt.Println(segment, it.Item().(*Paper).Title)
+		}
+		return it.Err()
+	})
+	if err != nil {
+		// Calling ParallelScan again with state resumes failed segments.
+	}
+}
+
+func ExampleCount() {
+	count, _, err := dynamodb.Count(context.Background(), "papers", dynamodb.Filter("#y >= :y", map[string]string{
+		"#y": "year",
+	}, types.AttributeValue{
+		":y": {"N": "2000"},
+	}))
+	if err != nil {
+		// ...
+	}
+	fmt.Println(count)
+}
+
 func ExamplePluck() {
 	items, err := dynamodb.Pluck("papers", &Paper{}, "title", "year")
 	if err != nil {
@@ -55,6 +109,57 @@ func ExamplePluck() {
 
 }
 
+func ExampleQuery() {
+	items, err := dynamodb.Query("papers", &Paper{
+		Title: "Dynamo: Amazon’s Highly Available Key-value Store",
+	})
+	if err != nil {
+		// ...
+	}
+	for _, paper := range items.([]*Paper) {
+		fmt.Println(paper.Year)
+	}
+}
+
+func ExampleIndex() {
+	type User struct {
+		ID    string `dynamo:"id,hash"`
+		Email string `dynamo:"email,gsi=ByEmail:hash"`
+	}
+	items, err := dynamodb.Query("users", &User{Email: "werner@example.com"}, dynamodb.Index("ByEmail"))
+	if err != nil {
+		// ...
+	}
+	fmt.Println(items.([]*User))
+}
+
+func ExampleTable() {
+	papers := dynamodb.NewTable[Paper](dynamodb.DefaultService, "papers")
+	paper, err := papers.Get(Paper{
+		Title: "Dynamo: Amazon’s Highly Available Key-value Store",
+		Year:  2007,
+	})
+	if err != nil {
+		// ...
+	}
+	fmt.Println(paper.Authors)
+}
+
+func ExampleProjectStruct() {
+	type summary struct {
+		Title string `dynamo:"title"`
+		Year  int    `dynamo:"year"`
+	}
+	paper := &Paper{
+		Title: "Dynamo: Amazon’s Highly Available Key-value Store",
+		Year:  2007,
+	}
+	err := dynamodb.Get("papers", paper, dynamodb.ProjectStruct(summary{}))
+	if err != nil {
+		// ...
+	}
+}
+
 func ExampleDelete() {
 	paper := &Paper{
 		Title: "Dynamo: Amazon’s Highly Available Key-value Store",
@@ -73,6 +178,62 @@ func ExampleCreateTable() {
 	}
 }
 
+func ExampleCreateTableWithOptions() {
+	protected := true
+	err := dynamodb.CreateTableWithOptions("papers", &Paper{}, &dynamodb.TableOptions{
+		BillingMode: dynamodb.BillingPayPerRequest,
+		TableClass:  dynamodb.TableClassInfrequentAccess,
+		Tags: []types.Tag{
+			{Key: "team", Value: "research"},
+		},
+		DeletionProtectionEnabled: &protected,
+	})
+	if err != nil {
+		// ...
+	}
+}
+
+func ExampleWaitForTable() {
+	if err := dynamodb.CreateTable("papers", &Paper{}, 1, 1); err != nil {
+		// ...
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	if err := dynamodb.WaitForTable(ctx, "papers", dynamodb.TableActive); err != nil {
+		// ...
+	}
+}
+
+func ExampleUpdateTable() {
+	err := dynamodb.UpdateTable("papers", &dynamodb.UpdateOptions{
+		ProvisionedThroughput: &types.ProvisionedThroughput{
+			ReadCapacityUnits:  10,
+			WriteCapacityUnits: 5,
+		},
+	})
+	if err != nil {
+		// ...
+	}
+}
+
+func ExamplePlanTable() {
+	opts := &dynamodb.TableOptions{
+		ProvisionedThroughput: &types.ProvisionedThroughput{
+			ReadCapacityUnits:  5,
+			WriteCapacityUnits: 5,
+		},
+	}
+	plan, err := dynamodb.PlanTable(context.Background(), "papers", &Paper{}, opts)
+	if err != nil {
+		// ...
+	}
+	// Dry run
+	fmt.Print(plan)
+	if err := dynamodb.Apply(context.Background(), plan); err != nil {
+		// ...
+	}
+}
+
 func ExampleListTables() {
 	tables, err := dynamodb.ListTables()
 	if err != nil {
@@ -0,0 +1,170 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+	"github.com/cyberdelia/dynamodb/types"
+	"reflect"
+)
+
+// A page of items as returned by Scan and Query.
+type page struct {
+	Items            []types.AttributeValue
+	LastEvaluatedKey types.AttributeValue
+	Count            int
+	ScannedCount     int
+	ConsumedCapacity *types.ConsumedCapacity
+}
+
+type fetchFunc func(startKey types.AttributeValue) (*page, error)
+
+// An Iterator walks through items one at a time, fetching a new page
+// only once the previous one has been consumed.
+//
+//	it := dynamodb.Scan(ctx, "papers", &Paper{})
+//	for it.Next() {
+//		paper := it.Item().(*Paper)
+//		// ...
+//	}
+//	if err := it.Err(); err != nil {
+//		// ...
+//	}
+type Iterator struct {
+	ctx     context.Context
+	decoder *types.Decoder
+	fetch   fetchFunc
+	t       reflect.Type
+	keys    []string
+
+	items   []types.AttributeValue // items left in the current page
+	key     types.AttributeValue   // key the next page starts after
+	resume  types.AttributeValue   // key to read the item being delivered again
+	done    bool
+	current types.AttributeValue
+	item    interface{}
+	err     error
+
+	// Strict errors of every item, when collecting them instead of
+	// stopping at the first one.
+	aggregate bool
+	n         int
+	errs      types.StrictError
+}
+
+func (s *Service) newIterator(ctx context.Context, item interface{}, o *options, fetch fetchFunc) *Iterator {
+	it := &Iterator{
+		ctx:     ctx,
+		decoder: s.decoder(),
+		fetch:   fetch,
+		t:       reflect.Indirect(reflect.ValueOf(item)).Type(),
+		key:     o.startKey,
+		resume:  o.startKey,
+	}
+	// Resuming from an index requires both the keys of the table and the
+	// keys of the index.
+	schema, _ := types.Keys(item)
+	if o.index != "" {
+		keys, _ := types.IndexKeys(item, o.index)
+		schema = append(schema, keys...)
+	}
+	seen := make(map[string]bool)
+	for _, key := range schema {
+		if !seen[key.AttributeName] {
+			seen[key.AttributeName] = true
+			it.keys = append(it.keys, key.AttributeName)
+		}
+	}
+	o.projectKeys(it.keys)
+	return it
+}
+
+// Advances to the next item, returning false when there are no more
+// items or an error occurred.
+func (it *Iterator) Next() bool {
+	it.resume = it.LastEvaluatedKey()
+	for it.err == nil {
+		if len(it.items) > 0 {
+			it.current, it.items = it.items[0], it.items[1:]
+			item := reflect.New(it.t).Interface()
+			err := it.decoder.Unmarshal(it.current, item)
+			n := it.n
+			it.n++
+			if errs, ok := err.(types.StrictError); ok && it.aggregate {
+				for _, e := range errs {
+					e.Path = fmt.Sprintf("[%d].%s", n, e.Path)
+				}
+				it.errs = append(it.errs, errs...)
+				continue
+			}
+			if it.err = err; it.err != nil {
+				return false
+			}
+			it.item = item
+			return true
+		}
+		if it.done {
+			break
+		}
+		if it.err = it.ctx.Err(); it.err != nil {
+			break
+		}
+		var p *page
+		if p, it.err = it.fetch(it.key); it.err != nil {
+			break
+		}
+		it.items, it.key = p.Items, p.LastEvaluatedKey
+		it.done = len(it.key) == 0
+	}
+	it.current, it.item = nil, nil
+	return false
+}
+
+// Returns the current item, decoded into a pointer to the type of the item
+// given to Scan.
+func (it *Iterator) Item() interface{} {
+	return it.item
+}
+
+// Returns the error, if any, that stopped the iteration.
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+// Reports whether every item has been read.
+func (it *Iterator) exhausted() bool {
+	return it.done && len(it.items) == 0
+}
+
+// Returns the key to resume the iteration from using StartKey, nil once
+// every item has been read.
+func (it *Iterator) LastEvaluatedKey() types.AttributeValue {
+	if len(it.items) == 0 || it.current == nil {
+		return it.key
+	}
+	// The current page is partially consumed, resume after the current item
+	key := make(types.AttributeValue)
+	for _, name := range it.keys {
+		if value, present := it.current[name]; present {
+			key[name] = value
+		}
+	}
+	return key
+}
+
+// collect reads every item left into a slice of item's type. Strict errors
+// of all items are reported together, their paths prefixed with the index
+// of the item, as in "[2].authors".
+func collect(it *Iterator, item interface{}) (interface{}, error) {
+	it.aggregate = true
+	slice := reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(item)), 0, 0)
+	for it.Next() {
+		slice = reflect.Append(slice, reflect.ValueOf(it.Item()))
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	if len(it.errs) > 0 {
+		return nil, it.errs
+	}
+	return slice.Interface(), nil
+}
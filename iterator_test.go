@@ -0,0 +1,108 @@
+package dynamodb
+
+import (
+	"context"
+	"github.com/cyberdelia/dynamodb/types"
+	"reflect"
+	"testing"
+)
+
+var pages = map[string]*page{
+	"": {
+		Items: []types.AttributeValue{
+			{"title": {"S": "a"}, "year": {"N": "2001"}},
+			{"title": {"S": "b"}, "year": {"N": "2002"}},
+		},
+		LastEvaluatedKey: types.AttributeValue{"title": {"S": "b"}, "year": {"N": "2002"}},
+	},
+	"b": {
+		Items: []types.AttributeValue{
+			{"title": {"S": "c"}, "year": {"N": "2003"}},
+		},
+	},
+}
+
+func fetchPages(startKey types.AttributeValue) (*page, error) {
+	var title string
+	if startKey != nil {
+		title = startKey["title"]["S"].(string)
+	}
+	return pages[title], nil
+}
+
+func TestIterator(t *testing.T) {
+	it := DefaultService.newIterator(context.Background(), &paper{}, new(options), fetchPages)
+	var titles []string
+	for it.Next() {
+		titles = append(titles, it.Item().(*paper).Title)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(titles, []string{"a", "b", "c"}) {
+		t.Errorf("got %v", titles)
+	}
+	if key := it.LastEvaluatedKey(); key != nil {
+		t.Errorf("got %v wants nil", key)
+	}
+}
+
+func TestIteratorResume(t *testing.T) {
+	it := DefaultService.newIterator(context.Background(), &paper{}, new(options), fetchPages)
+	if !it.Next() {
+		t.Fatal(it.Err())
+	}
+	key := it.LastEvaluatedKey()
+	control := types.AttributeValue{"title": {"S": "a"}, "year": {"N": "2001"}}
+	if !reflect.DeepEqual(key, control) {
+		t.Errorf("got %v wants %v", key, control)
+	}
+	it.Next()
+	it = DefaultService.newIterator(context.Background(), &paper{}, newOptions([]Option{StartKey(it.LastEvaluatedKey())}), fetchPages)
+	if !it.Next() {
+		t.Fatal(it.Err())
+	}
+	if title := it.Item().(*paper).Title; title != "c" {
+		t.Errorf("got %v wants %v", title, "c")
+	}
+}
+
+func TestIteratorCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	it := DefaultService.newIterator(ctx, &paper{}, new(options), fetchPages)
+	if it.Next() {
+		t.Error("iterated over canceled context")
+	}
+	if it.Err() != context.Canceled {
+		t.Errorf("got %v wants %v", it.Err(), context.Canceled)
+	}
+}
+
+func TestCollectStrict(t *testing.T) {
+	s := &Service{Strict: true}
+	fetch := func(startKey types.AttributeValue) (*page, error) {
+		if startKey == nil {
+			return &page{
+				Items: []types.AttributeValue{
+					{"title": {"S": "a"}, "year": {"S": "2001"}},
+					{"title": {"S": "b"}, "year": {"N": "2002"}},
+				},
+				LastEvaluatedKey: types.AttributeValue{"title": {"S": "b"}, "year": {"N": "2002"}},
+			}, nil
+		}
+		return &page{
+			Items: []types.AttributeValue{
+				{"title": {"S": "c"}, "year": {"N": "2003"}, "venue": {"S": "SOSP"}},
+			},
+		}, nil
+	}
+	_, err := collect(s.newIterator(context.Background(), &paper{}, new(options), fetch), &paper{})
+	control := types.StrictError{
+		{Path: "[0].year", Type: "S", Field: "Year", Want: "N"},
+		{Path: "[2].venue", Type: "S"},
+	}
+	if !reflect.DeepEqual(err, control) {
+		t.Errorf("got %v wants %v", err, control)
+	}
+}
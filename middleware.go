@@ -0,0 +1,88 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+type retriesKey struct{}
+
+// An Invoker performs the given action, decoding its response into a.
+type Invoker func(ctx context.Context, action string, body interface{}, a interface{}) error
+
+// A Middleware wraps the invoker of a service's actions. It can observe
+// or replace the body of requests and their decoded response or error,
+// return without calling next, or call it again to retry.
+//
+//	s.Middleware = append(s.Middleware, func(next dynamodb.Invoker) dynamodb.Invoker {
+//		return func(ctx context.Context, action string, body, a interface{}) error {
+//			start := time.Now()
+//			err := next(ctx, action, body, a)
+//			log.Printf("%s took %s: %v", action, time.Since(start), err)
+//			return err
+//		}
+//	})
+type Middleware func(next Invoker) Invoker
+
+// Chain wraps the given invoker with the given middlewares, the first one
+// being the outermost.
+func Chain(invoke Invoker, middlewares ...Middleware) Invoker {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		invoke = middlewares[i](invoke)
+	}
+	return invoke
+}
+
+// Returns a context counting the retries of the actions performed with it,
+// for outer middlewares to report them with Retries.
+func CountRetries(ctx context.Context) context.Context {
+	return context.WithValue(ctx, retriesKey{}, new(int64))
+}
+
+// Returns the number of retries counted in the given context.
+func Retries(ctx context.Context) int {
+	if n, ok := ctx.Value(retriesKey{}).(*int64); ok {
+		return int(atomic.LoadInt64(n))
+	}
+	return 0
+}
+
+// Retries actions failing with a throttling or server error, at most the
+// given number of times, waiting for the given delay, doubled on each
+// retry.
+func Retry(retries int, backoff time.Duration) Middleware {
+	return func(next Invoker) Invoker {
+		return func(ctx context.Context, action string, body interface{}, a interface{}) error {
+			delay := backoff
+			for i := 0; ; i++ {
+				err := next(ctx, action, body, a)
+				if err == nil || i >= retries || !retryable(err) {
+					return err
+				}
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(delay):
+				}
+				delay *= 2
+				if n, ok := ctx.Value(retriesKey{}).(*int64); ok {
+					atomic.AddInt64(n, 1)
+				}
+			}
+		}
+	}
+}
+
+func retryable(err error) bool {
+	var e *Error
+	if !errors.As(err, &e) {
+		return false
+	}
+	switch e.Type {
+	case "ProvisionedThroughputExceededException", "RequestLimitExceeded", "ThrottlingException":
+		return true
+	}
+	return e.StatusCode >= 500
+}
@@ -0,0 +1,117 @@
+package dynamodb
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Prefixes the name of tables of requests with the given prefix.
+func prefix(p string) Middleware {
+	return func(next Invoker) Invoker {
+		return func(ctx context.Context, action string, body interface{}, a interface{}) error {
+			b, err := json.Marshal(body)
+			if err != nil {
+				return err
+			}
+			var m map[string]interface{}
+			if err := json.Unmarshal(b, &m); err != nil {
+				return err
+			}
+			if name, ok := m["TableName"].(string); ok {
+				m["TableName"] = p + name
+			}
+			return next(ctx, action, m, a)
+		}
+	}
+}
+
+type itemResponse struct {
+	Item map[string]map[string]interface{}
+}
+
+func TestMiddleware(t *testing.T) {
+	var tables []string
+	s := testService(func(action string, body map[string]interface{}) (int, interface{}) {
+		tables = append(tables, body["TableName"].(string))
+		return 200, map[string]interface{}{
+			"Item": map[string]interface{}{
+				"title": map[string]string{"S": "Dynamo"},
+				"year":  map[string]string{"N": "2007"},
+			},
+		}
+	})
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(next Invoker) Invoker {
+			return func(ctx context.Context, action string, body interface{}, a interface{}) error {
+				calls = append(calls, name+" "+action)
+				err := next(ctx, action, body, a)
+				if resp, ok := a.(*itemResponse); ok && err == nil {
+					calls = append(calls, name+" "+resp.Item["title"]["S"].(string))
+				}
+				return err
+			}
+		}
+	}
+	s.Middleware = []Middleware{trace("outer"), prefix("test-"), trace("inner")}
+
+	var resp itemResponse
+	if err := s.Do("GetItem", map[string]interface{}{"TableName": "papers"}, &resp); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"outer GetItem", "inner GetItem", "inner Dynamo", "outer Dynamo"}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("expected calls %v, got %v", expected, calls)
+	}
+	if !reflect.DeepEqual(tables, []string{"test-papers"}) {
+		t.Errorf("expected a prefixed table, got %v", tables)
+	}
+
+	// Middlewares can answer without performing the action.
+	s.Middleware = append(s.Middleware, func(next Invoker) Invoker {
+		return func(ctx context.Context, action string, body interface{}, a interface{}) error {
+			return &Error{StatusCode: 400, Type: "AccessDeniedException", Message: "read only"}
+		}
+	})
+	if err := s.Put("papers", &paper{Title: "Dynamo", Year: 2007}); !IsError(err, "AccessDeniedException") {
+		t.Errorf("expected a denied access, got %v", err)
+	}
+	if len(tables) != 1 {
+		t.Errorf("expected no request, got %v", tables)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	n := 0
+	s := testService(func(action string, body map[string]interface{}) (int, interface{}) {
+		n++
+		if n < 3 {
+			return 400, map[string]string{
+				"__type":  "com.amazonaws.dynamodb.v20120810#ProvisionedThroughputExceededException",
+				"message": "slow down",
+			}
+		}
+		return 200, map[string]interface{}{"TableNames": []string{"papers"}}
+	})
+	s.Middleware = []Middleware{Retry(2, time.Millisecond)}
+	names, err := s.ListTables()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 || !reflect.DeepEqual(names, []string{"papers"}) {
+		t.Errorf("expected tables after 3 requests, got %v after %d", names, n)
+	}
+
+	n = 0
+	ctx := CountRetries(context.Background())
+	s.Middleware = []Middleware{Retry(1, time.Millisecond)}
+	if err := s.DoContext(ctx, "ListTables", struct{}{}, nil); !IsError(err, "ProvisionedThroughputExceededException") || n != 2 {
+		t.Errorf("expected a throttling error after 2 requests, got %v after %d", err, n)
+	}
+	if r := Retries(ctx); r != 1 {
+		t.Errorf("expected 1 retry, got %d", r)
+	}
+}
@@ -0,0 +1,368 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+	"github.com/cyberdelia/dynamodb/types"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// A Plan lists the steps bringing a table to the schema declared by a
+// struct, printing them is a dry run of the migration.
+type Plan struct {
+	TableName string
+	Steps     []*Step
+
+	item interface{}
+}
+
+// A Step is a single change of a table, either its creation, an update or
+// a change of its time to live.
+type Step struct {
+	Description string
+	Create      *TableOptions
+	Update      *UpdateOptions
+	TimeToLive  *types.TimeToLiveSpecification
+}
+
+func (p *Plan) String() string {
+	if len(p.Steps) == 0 {
+		return fmt.Sprintf("%s: up to date\n", p.TableName)
+	}
+	var b strings.Builder
+	for i, step := range p.Steps {
+		fmt.Fprintf(&b, "%s: %d. %s\n", p.TableName, i+1, step.Description)
+	}
+	return b.String()
+}
+
+// Computes the plan bringing the given table to the schema declared by the
+// given item and options.
+//
+// Keys of the table and its local secondary indexes can't be changed once
+// created, and DynamoDB only creates or deletes one global secondary index
+// at a time, each of those being a separate step.
+func (s *Service) PlanTable(ctx context.Context, tableName string, item interface{}, opts *TableOptions) (*Plan, error) {
+	if opts == nil {
+		opts = &TableOptions{}
+	}
+	plan := &Plan{
+		TableName: tableName,
+		item:      item,
+	}
+	ttl, err := types.TimeToLive(item)
+	if err != nil {
+		return nil, err
+	}
+	table, err := s.describeTable(ctx, tableName)
+	if IsError(err, "ResourceNotFoundException") {
+		plan.Steps = append(plan.Steps, &Step{
+			Description: "create table",
+			Create:      opts,
+		})
+		if ttl != "" {
+			plan.timeToLive(ttl)
+		}
+		return plan, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := types.Keys(item)
+	if err != nil {
+		return nil, err
+	}
+	if !sameKeys(keys, table.KeySchema) {
+		return nil, fmt.Errorf("dynamodb: key schema of table %s can't be changed", tableName)
+	}
+	global, local, err := types.Indexes(item)
+	if err != nil {
+		return nil, err
+	}
+	if !sameLocalIndexes(local, table.LocalSecondaryIndexes) {
+		return nil, fmt.Errorf("dynamodb: local secondary indexes of table %s can't be changed", tableName)
+	}
+	definitions, err := types.Definitions(item)
+	if err != nil {
+		return nil, err
+	}
+
+	billing := opts.BillingMode
+	if billing == "" {
+		billing = BillingProvisioned
+	}
+	current := BillingProvisioned
+	if table.BillingModeSummary != nil && table.BillingModeSummary.BillingMode != "" {
+		current = table.BillingModeSummary.BillingMode
+	}
+	if billing == BillingProvisioned && opts.ProvisionedThroughput == nil {
+		return nil, ErrThroughput
+	}
+
+	existing := make(map[string]types.GlobalSecondaryIndexDescription)
+	for _, index := range table.GlobalSecondaryIndexes {
+		existing[index.IndexName] = index
+	}
+	desired := make(map[string]bool)
+	var created []types.GlobalSecondaryIndex
+	var kept []string
+	for _, index := range global {
+		desired[index.IndexName] = true
+		e, present := existing[index.IndexName]
+		switch {
+		case !present:
+			created = append(created, index)
+		case !sameIndex(index.KeySchema, index.Projection, e.KeySchema, e.Projection):
+			// Indexes can't be altered, only replaced
+			delete(existing, index.IndexName)
+			plan.delete(index.IndexName)
+			created = append(created, index)
+		default:
+			kept = append(kept, index.IndexName)
+		}
+	}
+	var deleted []string
+	for name := range existing {
+		if !desired[name] {
+			deleted = append(deleted, name)
+		}
+	}
+	sort.Strings(deleted)
+	for _, name := range deleted {
+		plan.delete(name)
+	}
+
+	throughput := func(names []string) (updates []types.GlobalSecondaryIndexUpdate) {
+		for _, name := range names {
+			if billing == current && existing[name].ProvisionedThroughput.ReadCapacityUnits == opts.ProvisionedThroughput.ReadCapacityUnits &&
+				existing[name].ProvisionedThroughput.WriteCapacityUnits == opts.ProvisionedThroughput.WriteCapacityUnits {
+				continue
+			}
+			updates = append(updates, types.GlobalSecondaryIndexUpdate{
+				Update: &types.UpdateGlobalSecondaryIndexAction{
+					IndexName:             name,
+					ProvisionedThroughput: *opts.ProvisionedThroughput,
+				},
+			})
+		}
+		return updates
+	}
+	switch {
+	case billing != current && billing == BillingPayPerRequest:
+		plan.update(fmt.Sprintf("switch billing mode to %s", billing), &UpdateOptions{
+			BillingMode: billing,
+		})
+	case billing != current:
+		plan.update(fmt.Sprintf("switch billing mode to %s", billing), &UpdateOptions{
+			BillingMode:                 billing,
+			ProvisionedThroughput:       opts.ProvisionedThroughput,
+			GlobalSecondaryIndexUpdates: throughput(kept),
+		})
+	case billing == BillingProvisioned:
+		var update UpdateOptions
+		if table.ProvisionedThroughput.ReadCapacityUnits != opts.ProvisionedThroughput.ReadCapacityUnits ||
+			table.ProvisionedThroughput.WriteCapacityUnits != opts.ProvisionedThroughput.WriteCapacityUnits {
+			update.ProvisionedThroughput = opts.ProvisionedThroughput
+		}
+		update.GlobalSecondaryIndexUpdates = throughput(kept)
+		if update.ProvisionedThroughput != nil || len(update.GlobalSecondaryIndexUpdates) > 0 {
+			plan.update(fmt.Sprintf("update throughput to %d read and %d write units",
+				opts.ProvisionedThroughput.ReadCapacityUnits, opts.ProvisionedThroughput.WriteCapacityUnits), &update)
+		}
+	}
+
+	for _, index := range created {
+		index := index
+		if billing == BillingProvisioned {
+			index.ProvisionedThroughput = opts.ProvisionedThroughput
+		}
+		plan.update(fmt.Sprintf("create index %s", index.IndexName), &UpdateOptions{
+			AttributeDefinitions: definitions,
+			GlobalSecondaryIndexUpdates: []types.GlobalSecondaryIndexUpdate{
+				{Create: &index},
+			},
+		})
+	}
+
+	if stream := opts.StreamSpecification; stream != nil && !sameStream(stream, table.StreamSpecification) {
+		if table.StreamSpecification != nil && table.StreamSpecification.StreamEnabled && stream.StreamEnabled {
+			// The view type of a stream can't be changed while enabled
+			plan.update("disable stream", &UpdateOptions{
+				StreamSpecification: &types.StreamSpecification{StreamEnabled: false},
+			})
+		}
+		description := "disable stream"
+		if stream.StreamEnabled {
+			description = fmt.Sprintf("enable %s stream", stream.StreamViewType)
+		}
+		plan.update(description, &UpdateOptions{
+			StreamSpecification: stream,
+		})
+	}
+	if opts.TableClass != "" && (table.TableClassSummary == nil || table.TableClassSummary.TableClass != opts.TableClass) {
+		plan.update(fmt.Sprintf("switch table class to %s", opts.TableClass), &UpdateOptions{
+			TableClass: opts.TableClass,
+		})
+	}
+	if protection := opts.DeletionProtectionEnabled; protection != nil && *protection != table.DeletionProtectionEnabled {
+		description := "disable deletion protection"
+		if *protection {
+			description = "enable deletion protection"
+		}
+		plan.update(description, &UpdateOptions{
+			DeletionProtectionEnabled: protection,
+		})
+	}
+
+	if ttl == "" {
+		return plan, nil
+	}
+	description, err := s.describeTimeToLive(ctx, tableName)
+	if err != nil {
+		return nil, err
+	}
+	switch description.TimeToLiveStatus {
+	case "ENABLED", "ENABLING":
+		if description.AttributeName != ttl {
+			return nil, fmt.Errorf("dynamodb: time to live of table %s is enabled on %s", tableName, description.AttributeName)
+		}
+	case "DISABLING":
+		// DynamoDB rejects changes until time to live is disabled
+		return nil, fmt.Errorf("dynamodb: time to live of table %s is being disabled", tableName)
+	default:
+		plan.timeToLive(ttl)
+	}
+	return plan, nil
+}
+
+// Computes the plan bringing the given table to the schema declared by the
+// given item and options.
+func PlanTable(ctx context.Context, tableName string, item interface{}, opts *TableOptions) (*Plan, error) {
+	return DefaultService.PlanTable(ctx, tableName, item, opts)
+}
+
+func (p *Plan) update(description string, update *UpdateOptions) {
+	p.Steps = append(p.Steps, &Step{
+		Description: description,
+		Update:      update,
+	})
+}
+
+func (p *Plan) timeToLive(attribute string) {
+	p.Steps = append(p.Steps, &Step{
+		Description: fmt.Sprintf("enable time to live on %s", attribute),
+		TimeToLive: &types.TimeToLiveSpecification{
+			AttributeName: attribute,
+			Enabled:       true,
+		},
+	})
+}
+
+func (p *Plan) delete(index string) {
+	p.update(fmt.Sprintf("delete index %s", index), &UpdateOptions{
+		GlobalSecondaryIndexUpdates: []types.GlobalSecondaryIndexUpdate{
+			{Delete: &types.DeleteGlobalSecondaryIndexAction{IndexName: index}},
+		},
+	})
+}
+
+// Applies each step of the given plan, waiting for the table and its
+// indexes to be active before moving on to the next step.
+func (s *Service) Apply(ctx context.Context, plan *Plan) error {
+	for _, step := range plan.Steps {
+		var err error
+		switch {
+		case step.Create != nil:
+			err = s.CreateTableWithOptions(plan.TableName, plan.item, step.Create)
+		case step.TimeToLive != nil:
+			err = s.UpdateTimeToLive(plan.TableName, step.TimeToLive.AttributeName, step.TimeToLive.Enabled)
+		default:
+			err = s.UpdateTable(plan.TableName, step.Update)
+		}
+		if err != nil {
+			return fmt.Errorf("dynamodb: %s: %s: %w", plan.TableName, step.Description, err)
+		}
+		if err := s.WaitForTable(ctx, plan.TableName, TableActive); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Applies each step of the given plan.
+func Apply(ctx context.Context, plan *Plan) error {
+	return DefaultService.Apply(ctx, plan)
+}
+
+// Creates or updates the given table to match the schema declared by the
+// given item and options, returning the plan that was applied.
+func (s *Service) EnsureTable(ctx context.Context, tableName string, item interface{}, opts *TableOptions) (*Plan, error) {
+	plan, err := s.PlanTable(ctx, tableName, item, opts)
+	if err != nil {
+		return nil, err
+	}
+	return plan, s.Apply(ctx, plan)
+}
+
+// Creates or updates the given table to match the schema declared by the
+// given item and options, returning the plan that was applied.
+func EnsureTable(ctx context.Context, tableName string, item interface{}, opts *TableOptions) (*Plan, error) {
+	return DefaultService.EnsureTable(ctx, tableName, item, opts)
+}
+
+func sameIndex(keys types.KeySchema, projection types.Projection, otherKeys types.KeySchema, otherProjection types.Projection) bool {
+	if !sameKeys(keys, otherKeys) || projection.ProjectionType != otherProjection.ProjectionType {
+		return false
+	}
+	a := append([]string(nil), projection.NonKeyAttributes...)
+	b := append([]string(nil), otherProjection.NonKeyAttributes...)
+	sort.Strings(a)
+	sort.Strings(b)
+	return len(a) == len(b) && (len(a) == 0 || reflect.DeepEqual(a, b))
+}
+
+func sameKeys(a, b types.KeySchema) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	keys := make(map[string]string)
+	for _, key := range a {
+		keys[key.AttributeName] = key.KeyType
+	}
+	for _, key := range b {
+		if keys[key.AttributeName] != key.KeyType {
+			return false
+		}
+	}
+	return true
+}
+
+func sameLocalIndexes(local []types.LocalSecondaryIndex, existing []types.LocalSecondaryIndexDescription) bool {
+	if len(local) != len(existing) {
+		return false
+	}
+	indexes := make(map[string]types.LocalSecondaryIndexDescription)
+	for _, index := range existing {
+		indexes[index.IndexName] = index
+	}
+	for _, index := range local {
+		e, present := indexes[index.IndexName]
+		if !present || !sameIndex(index.KeySchema, index.Projection, e.KeySchema, e.Projection) {
+			return false
+		}
+	}
+	return true
+}
+
+func sameStream(a, b *types.StreamSpecification) bool {
+	enabled := func(s *types.StreamSpecification) bool {
+		return s != nil && s.StreamEnabled
+	}
+	if !enabled(a) || !enabled(b) {
+		return enabled(a) == enabled(b)
+	}
+	return a.StreamViewType == b.StreamViewType
+}
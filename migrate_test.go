@@ -0,0 +1,276 @@
+package dynamodb
+
+import (
+	"context"
+	"github.com/cyberdelia/dynamodb/types"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type migrated struct {
+	ID    string `dynamo:"id,hash"`
+	Email string `dynamo:"email,gsi=ByEmail:hash"`
+	Name  string `dynamo:"name,gsi=ByName:hash:keys_only"`
+}
+
+var migratedTable = map[string]interface{}{
+	"TableName":   "users",
+	"TableStatus": "ACTIVE",
+	"KeySchema": []interface{}{
+		map[string]interface{}{"AttributeName": "id", "KeyType": "HASH"},
+	},
+	"ProvisionedThroughput": map[string]interface{}{
+		"ReadCapacityUnits":  1,
+		"WriteCapacityUnits": 1,
+	},
+	"GlobalSecondaryIndexes": []interface{}{
+		map[string]interface{}{
+			"IndexName":   "ByName",
+			"IndexStatus": "ACTIVE",
+			"KeySchema": []interface{}{
+				map[string]interface{}{"AttributeName": "name", "KeyType": "HASH"},
+			},
+			"Projection": map[string]interface{}{"ProjectionType": "ALL"},
+			"ProvisionedThroughput": map[string]interface{}{
+				"ReadCapacityUnits":  1,
+				"WriteCapacityUnits": 1,
+			},
+		},
+		map[string]interface{}{
+			"IndexName":   "ByAge",
+			"IndexStatus": "ACTIVE",
+			"KeySchema": []interface{}{
+				map[string]interface{}{"AttributeName": "age", "KeyType": "HASH"},
+			},
+			"Projection": map[string]interface{}{"ProjectionType": "ALL"},
+		},
+	},
+}
+
+func TestPlanTable(t *testing.T) {
+	s := testService(func(action string, body map[string]interface{}) (int, interface{}) {
+		return 200, map[string]interface{}{"Table": migratedTable}
+	})
+	protected := true
+	plan, err := s.PlanTable(context.Background(), "users", &migrated{}, &TableOptions{
+		ProvisionedThroughput: &types.ProvisionedThroughput{
+			ReadCapacityUnits:  2,
+			WriteCapacityUnits: 1,
+		},
+		DeletionProtectionEnabled: &protected,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var steps []string
+	for _, step := range plan.Steps {
+		steps = append(steps, step.Description)
+	}
+	control := []string{
+		"delete index ByName",
+		"delete index ByAge",
+		"update throughput to 2 read and 1 write units",
+		"create index ByEmail",
+		"create index ByName",
+		"enable deletion protection",
+	}
+	if !reflect.DeepEqual(steps, control) {
+		t.Errorf("got %v wants %v", steps, control)
+	}
+	for _, step := range plan.Steps {
+		if n := len(step.Update.GlobalSecondaryIndexUpdates); n > 1 {
+			t.Errorf("step %q changes %d indexes", step.Description, n)
+		}
+	}
+	if _, err := s.PlanTable(context.Background(), "users", &migrated{}, nil); err != ErrThroughput {
+		t.Errorf("got %v wants %v", err, ErrThroughput)
+	}
+}
+
+func TestPlanTableUnchanged(t *testing.T) {
+	s := testService(func(action string, body map[string]interface{}) (int, interface{}) {
+		return 200, map[string]interface{}{
+			"Table": map[string]interface{}{
+				"TableName":                 "users",
+				"TableStatus":               "ACTIVE",
+				"KeySchema":                 []interface{}{map[string]interface{}{"AttributeName": "id", "KeyType": "HASH"}},
+				"BillingModeSummary":        map[string]interface{}{"BillingMode": "PAY_PER_REQUEST"},
+				"StreamSpecification":       map[string]interface{}{"StreamEnabled": true, "StreamViewType": "NEW_IMAGE"},
+				"DeletionProtectionEnabled": true,
+			},
+		}
+	})
+	type user struct {
+		ID string `dynamo:"id,hash"`
+	}
+	plan, err := s.PlanTable(context.Background(), "users", &user{}, &TableOptions{
+		BillingMode: BillingPayPerRequest,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Steps) != 0 {
+		t.Errorf("got %v wants no steps", plan)
+	}
+	protected := false
+	plan, err = s.PlanTable(context.Background(), "users", &user{}, &TableOptions{
+		BillingMode:               BillingPayPerRequest,
+		StreamSpecification:       &types.StreamSpecification{},
+		DeletionProtectionEnabled: &protected,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.String() != "users: 1. disable stream\nusers: 2. disable deletion protection\n" {
+		t.Errorf("got %q", plan.String())
+	}
+}
+
+func TestPlanTableKeys(t *testing.T) {
+	s := testService(func(action string, body map[string]interface{}) (int, interface{}) {
+		return 200, map[string]interface{}{"Table": migratedTable}
+	})
+	type renamed struct {
+		ID string `dynamo:"key,hash"`
+	}
+	_, err := s.PlanTable(context.Background(), "users", &renamed{}, &TableOptions{
+		BillingMode: BillingPayPerRequest,
+	})
+	if err == nil {
+		t.Error("changed key schema wasn't rejected")
+	}
+}
+
+func TestEnsureTable(t *testing.T) {
+	var actions []string
+	s := testService(func(action string, body map[string]interface{}) (int, interface{}) {
+		actions = append(actions, action)
+		if len(actions) == 1 {
+			return 400, map[string]interface{}{
+				"__type":  "com.amazonaws.dynamodb.v20120810#ResourceNotFoundException",
+				"message": "Requested resource not found",
+			}
+		}
+		return 200, map[string]interface{}{
+			"Table":            map[string]interface{}{"TableStatus": "ACTIVE"},
+			"TableDescription": map[string]interface{}{"TableStatus": "CREATING"},
+		}
+	})
+	plan, err := s.EnsureTable(context.Background(), "users", &migrated{}, &TableOptions{
+		BillingMode: BillingPayPerRequest,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.String() != "users: 1. create table\n" {
+		t.Errorf("got %q", plan.String())
+	}
+	control := []string{"DescribeTable", "CreateTable", "DescribeTable"}
+	if !reflect.DeepEqual(actions, control) {
+		t.Errorf("got %v wants %v", actions, control)
+	}
+}
+
+type expiring struct {
+	ID      string    `dynamo:"id,hash"`
+	Expires time.Time `dynamo:"expires,ttl"`
+}
+
+func TestPlanTableTimeToLive(t *testing.T) {
+	s := testService(func(action string, body map[string]interface{}) (int, interface{}) {
+		if action == "DescribeTimeToLive" {
+			return 200, map[string]interface{}{
+				"TimeToLiveDescription": map[string]interface{}{"TimeToLiveStatus": "DISABLED"},
+			}
+		}
+		return 200, map[string]interface{}{
+			"Table": map[string]interface{}{
+				"TableName":   "sessions",
+				"TableStatus": "ACTIVE",
+				"KeySchema": []interface{}{
+					map[string]interface{}{"AttributeName": "id", "KeyType": "HASH"},
+				},
+				"BillingModeSummary": map[string]interface{}{"BillingMode": "PAY_PER_REQUEST"},
+			},
+		}
+	})
+	type key struct{}
+	var missing []string
+	s.Middleware = []Middleware{func(next Invoker) Invoker {
+		return func(ctx context.Context, action string, body, a interface{}) error {
+			if ctx.Value(key{}) == nil {
+				missing = append(missing, action)
+			}
+			return next(ctx, action, body, a)
+		}
+	}}
+	ctx := context.WithValue(context.Background(), key{}, true)
+	plan, err := s.PlanTable(ctx, "sessions", &expiring{}, &TableOptions{
+		BillingMode: BillingPayPerRequest,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Steps) != 1 || plan.Steps[0].TimeToLive == nil || plan.Steps[0].TimeToLive.AttributeName != "expires" {
+		t.Errorf("got %v", plan)
+	}
+	if len(missing) > 0 {
+		t.Errorf("got %v performed without the context", missing)
+	}
+}
+
+func TestPlanTableTimeToLiveDisabling(t *testing.T) {
+	s := testService(func(action string, body map[string]interface{}) (int, interface{}) {
+		if action == "DescribeTimeToLive" {
+			return 200, map[string]interface{}{
+				"TimeToLiveDescription": map[string]interface{}{"TimeToLiveStatus": "DISABLING"},
+			}
+		}
+		return 200, map[string]interface{}{
+			"Table": map[string]interface{}{
+				"TableName":          "sessions",
+				"TableStatus":        "ACTIVE",
+				"KeySchema":          []interface{}{map[string]interface{}{"AttributeName": "id", "KeyType": "HASH"}},
+				"BillingModeSummary": map[string]interface{}{"BillingMode": "PAY_PER_REQUEST"},
+			},
+		}
+	})
+	_, err := s.PlanTable(context.Background(), "sessions", &expiring{}, &TableOptions{
+		BillingMode: BillingPayPerRequest,
+	})
+	if err == nil {
+		t.Error("got no error wants an error for a table disabling time to live")
+	}
+}
+
+func TestEnsureTableTimeToLive(t *testing.T) {
+	var actions []string
+	var ttl interface{}
+	s := testService(func(action string, body map[string]interface{}) (int, interface{}) {
+		actions = append(actions, action)
+		if action == "UpdateTimeToLive" {
+			ttl = body["TimeToLiveSpecification"]
+		}
+		if len(actions) == 1 {
+			return 400, map[string]string{
+				"__type":  "com.amazonaws.dynamodb.v20120810#ResourceNotFoundException",
+				"message": "not found",
+			}
+		}
+		return 200, map[string]interface{}{
+			"Table": map[string]interface{}{"TableStatus": "ACTIVE"},
+		}
+	})
+	if _, err := s.EnsureTable(context.Background(), "sessions", &expiring{}, &TableOptions{BillingMode: BillingPayPerRequest}); err != nil {
+		t.Fatal(err)
+	}
+	control := []string{"DescribeTable", "CreateTable", "DescribeTable", "UpdateTimeToLive", "DescribeTable"}
+	if !reflect.DeepEqual(actions, control) {
+		t.Errorf("got %v wants %v", actions, control)
+	}
+	spec := map[string]interface{}{"AttributeName": "expires", "Enabled": true}
+	if !reflect.DeepEqual(ttl, spec) {
+		t.Errorf("got %v wants %v", ttl, spec)
+	}
+}
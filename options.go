@@ -0,0 +1,169 @@
+package dynamodb
+
+import (
+	"github.com/cyberdelia/dynamodb/types"
+)
+
+// An Option configures a read operation.
+type Option func(*options)
+
+type options struct {
+	startKey      types.AttributeValue
+	index         string
+	limit         int
+	selection     string
+	filter        string
+	paths         []string
+	projection    string
+	consistent    bool
+	capacity      string
+	consumed      func(*types.ConsumedCapacity)
+	names         map[string]string
+	values        types.AttributeValue
+	segment       int
+	totalSegments int
+	err           error
+}
+
+func newOptions(opts []Option) *options {
+	o := new(options)
+	for _, opt := range opts {
+		opt(o)
+	}
+	if len(o.paths) > 0 && o.err == nil {
+		// Placeholders of the projection skip those of the filter, whatever
+		// the order of options
+		names := merge(nil, o.names)
+		if names == nil {
+			names = make(map[string]string)
+		}
+		o.projection, o.err = projection(o.paths, names)
+		o.names = names
+	}
+	return o
+}
+
+// Start reading right after the given key, as returned by
+// Iterator.LastEvaluatedKey.
+func StartKey(key types.AttributeValue) Option {
+	return func(o *options) {
+		o.startKey = key
+	}
+}
+
+// Read from the given secondary index instead of the table. Queries match
+// the keys of the index.
+func Index(name string) Option {
+	return func(o *options) {
+		o.index = name
+	}
+}
+
+// Values accepted by Select.
+const (
+	SelectAll       = "ALL_ATTRIBUTES"
+	SelectProjected = "ALL_PROJECTED_ATTRIBUTES"
+	SelectSpecific  = "SPECIFIC_ATTRIBUTES"
+	SelectCount     = "COUNT"
+)
+
+// Select the attributes to return, either SelectAll, SelectProjected,
+// SelectSpecific or SelectCount.
+func Select(selection string) Option {
+	return func(o *options) {
+		o.selection = selection
+	}
+}
+
+// Only return items matching the given filter expression, using the given
+// expression attribute names and values, queries reserving the #k0 and :k0
+// placeholders, and so on, for their key condition:
+//
+//	dynamodb.Filter("#y > :y", map[string]string{"#y": "year"}, types.AttributeValue{
+//		":y": {"N": "2000"},
+//	})
+func Filter(expression string, names map[string]string, values types.AttributeValue) Option {
+	return func(o *options) {
+		o.filter = expression
+		o.names = merge(o.names, names)
+		o.values = mergeValues(o.values, values)
+	}
+}
+
+// Values accepted by ReturnConsumedCapacity.
+const (
+	CapacityTotal   = "TOTAL"
+	CapacityIndexes = "INDEXES"
+)
+
+// Use strongly consistent reads instead of eventually consistent ones.
+func ConsistentRead() Option {
+	return func(o *options) {
+		o.consistent = true
+	}
+}
+
+// Report the capacity consumed by each request to fn, either in total
+// with CapacityTotal or detailed for each index with CapacityIndexes.
+func ReturnConsumedCapacity(mode string, fn func(*types.ConsumedCapacity)) Option {
+	return func(o *options) {
+		o.capacity = mode
+		o.consumed = fn
+	}
+}
+
+func (o *options) report(c *types.ConsumedCapacity) {
+	if o.consumed != nil && c != nil {
+		o.consumed(c)
+	}
+}
+
+// Evaluate at most n items per request, before any filter is applied.
+func Limit(n int) Option {
+	return func(o *options) {
+		o.limit = n
+	}
+}
+
+// Scan only the given segment out of total segments.
+func Segment(segment, total int) Option {
+	return func(o *options) {
+		o.segment = segment
+		o.totalSegments = total
+	}
+}
+
+func (o *options) segmentValue() *int {
+	if o.totalSegments == 0 {
+		return nil
+	}
+	return &o.segment
+}
+
+func merge(a, b map[string]string) map[string]string {
+	if len(b) == 0 {
+		return a
+	}
+	m := make(map[string]string, len(a)+len(b))
+	for k, v := range a {
+		m[k] = v
+	}
+	for k, v := range b {
+		m[k] = v
+	}
+	return m
+}
+
+func mergeValues(a, b types.AttributeValue) types.AttributeValue {
+	if len(b) == 0 {
+		return a
+	}
+	m := make(types.AttributeValue, len(a)+len(b))
+	for k, v := range a {
+		m[k] = v
+	}
+	for k, v := range b {
+		m[k] = v
+	}
+	return m
+}
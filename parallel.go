@@ -0,0 +1,132 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/cyberdelia/dynamodb/types"
+	"sync"
+)
+
+// A ScanState records the progress of a parallel scan, allowing it to be
+// resumed after a failure.
+type ScanState struct {
+	TotalSegments int
+
+	// Segments left to scan, with the key to resume each of them from,
+	// nil for segments that haven't been started.
+	Pending map[int]types.AttributeValue
+
+	mu sync.Mutex
+}
+
+// Returns the state of a parallel scan of the given number of segments
+// that hasn't started yet.
+func NewScanState(segments int) *ScanState {
+	pending := make(map[int]types.AttributeValue)
+	for i := 0; i < segments; i++ {
+		pending[i] = nil
+	}
+	return &ScanState{
+		TotalSegments: segments,
+		Pending:       pending,
+	}
+}
+
+// Reports whether every segment has been scanned.
+func (s *ScanState) Done() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.Pending) == 0
+}
+
+func (s *ScanState) update(segment int, it *Iterator, failed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case it.exhausted() && (!failed || it.current == nil && it.err == nil):
+		// Every item was read, and handled unless fn failed on the last one
+		delete(s.Pending, segment)
+	case failed:
+		// Resume from the last item delivered successfully, reading the one
+		// that failed again
+		s.Pending[segment] = it.resume
+	default:
+		s.Pending[segment] = it.LastEvaluatedKey()
+	}
+}
+
+// A SegmentError records the failure of a segment of a parallel scan.
+type SegmentError struct {
+	Segment int
+	Err     error
+}
+
+func (e *SegmentError) Error() string {
+	return fmt.Sprintf("dynamodb: segment %d: %s", e.Segment, e.Err)
+}
+
+func (e *SegmentError) Unwrap() error {
+	return e.Err
+}
+
+// Scan the pending segments of the given table concurrently, running at
+// most workers segments at a time, with the given options. fn is called
+// with the iterator of each segment and may be called concurrently.
+//
+// The state is updated as segments complete, and errors of every failed
+// segment are returned as SegmentError joined together. Calling
+// ParallelScan again with the same state resumes the failed segments,
+// starting with the item they failed on.
+func (s *Service) ParallelScan(ctx context.Context, tableName string, item interface{}, state *ScanState, workers int, fn func(segment int, it *Iterator) error, opts ...Option) error {
+	state.mu.Lock()
+	segments := make(map[int]types.AttributeValue, len(state.Pending))
+	for segment, key := range state.Pending {
+		segments[segment] = key
+	}
+	state.mu.Unlock()
+
+	if workers <= 0 || workers > len(segments) {
+		workers = len(segments)
+	}
+	sem := make(chan struct{}, workers)
+
+	var mu sync.Mutex
+	var errs []error
+	var wg sync.WaitGroup
+	for segment, key := range segments {
+		wg.Add(1)
+		go func(segment int, key types.AttributeValue) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				mu.Lock()
+				errs = append(errs, &SegmentError{Segment: segment, Err: ctx.Err()})
+				mu.Unlock()
+				return
+			}
+			segmentOpts := append(append([]Option(nil), opts...), StartKey(key), Segment(segment, state.TotalSegments))
+			it := s.Scan(ctx, tableName, item, segmentOpts...)
+			err := fn(segment, it)
+			if err == nil {
+				err = it.Err()
+			}
+			state.update(segment, it, err != nil)
+			if err != nil {
+				mu.Lock()
+				errs = append(errs, &SegmentError{Segment: segment, Err: err})
+				mu.Unlock()
+			}
+		}(segment, key)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// Scan the pending segments of the given table concurrently, running at
+// most workers segments at a time, with the given options.
+func ParallelScan(ctx context.Context, tableName string, item interface{}, state *ScanState, workers int, fn func(segment int, it *Iterator) error, opts ...Option) error {
+	return DefaultService.ParallelScan(ctx, tableName, item, state, workers, fn, opts...)
+}
@@ -0,0 +1,115 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"github.com/cyberdelia/dynamodb/types"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestParallelScan(t *testing.T) {
+	s := testService(func(action string, body map[string]interface{}) (int, interface{}) {
+		segment := int(body["Segment"].(float64))
+		if segment == 2 {
+			return 400, map[string]string{
+				"__type":  "ProvisionedThroughputExceededException",
+				"message": "slow down",
+			}
+		}
+		return 200, map[string]interface{}{
+			"Items": []interface{}{
+				map[string]interface{}{
+					"title": map[string]string{"S": "a"},
+					"year":  map[string]string{"N": "2000"},
+				},
+			},
+		}
+	})
+	state := NewScanState(4)
+	var mu sync.Mutex
+	var segments []int
+	err := s.ParallelScan(context.Background(), "papers", &paper{}, state, 2, func(segment int, it *Iterator) error {
+		for it.Next() {
+			mu.Lock()
+			segments = append(segments, segment)
+			mu.Unlock()
+		}
+		return nil
+	})
+	var serr *SegmentError
+	if !errors.As(err, &serr) || serr.Segment != 2 {
+		t.Fatalf("got %v wants segment 2 error", err)
+	}
+	sort.Ints(segments)
+	if len(segments) != 3 || segments[0] != 0 || segments[2] != 3 {
+		t.Errorf("got %v", segments)
+	}
+	if _, pending := state.Pending[2]; !pending || len(state.Pending) != 1 {
+		t.Errorf("got %v wants segment 2 pending", state.Pending)
+	}
+}
+
+func TestParallelScanResume(t *testing.T) {
+	var filters []interface{}
+	s := testService(func(action string, body map[string]interface{}) (int, interface{}) {
+		filters = append(filters, body["FilterExpression"])
+		item := func(title string) map[string]interface{} {
+			return map[string]interface{}{
+				"title": map[string]string{"S": title},
+				"year":  map[string]string{"N": "2000"},
+			}
+		}
+		if body["ExclusiveStartKey"] == nil {
+			return 200, map[string]interface{}{
+				"Items":            []interface{}{item("a"), item("b")},
+				"LastEvaluatedKey": item("b"),
+			}
+		}
+		start := body["ExclusiveStartKey"].(map[string]interface{})["title"].(map[string]interface{})["S"]
+		if start == "a" {
+			return 200, map[string]interface{}{
+				"Items":            []interface{}{item("b")},
+				"LastEvaluatedKey": item("b"),
+			}
+		}
+		return 200, map[string]interface{}{
+			"Items": []interface{}{item("c")},
+		}
+	})
+	filter := Filter("#y > :y", map[string]string{"#y": "year"}, types.AttributeValue{":y": {"N": "1999"}})
+	fail := "b"
+	var titles []string
+	scan := func(segment int, it *Iterator) error {
+		for it.Next() {
+			title := it.Item().(*paper).Title
+			if title == fail {
+				return errors.New("failure")
+			}
+			titles = append(titles, title)
+		}
+		return it.Err()
+	}
+	state := NewScanState(1)
+	if err := s.ParallelScan(context.Background(), "papers", &paper{}, state, 1, scan, filter); err == nil {
+		t.Fatal("got no error wants a failure")
+	}
+	key := state.Pending[0]
+	if key["title"]["S"] != "a" {
+		t.Errorf("got %v wants the key of a", key)
+	}
+	fail = ""
+	if err := s.ParallelScan(context.Background(), "papers", &paper{}, state, 1, scan, filter); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(titles, []string{"a", "b", "c"}) || !state.Done() {
+		t.Errorf("got %v wants %v", titles, []string{"a", "b", "c"})
+	}
+	for _, f := range filters {
+		if f != "#y > :y" {
+			t.Errorf("got %v wants %v", f, "#y > :y")
+		}
+	}
+}
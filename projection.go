@@ -0,0 +1,136 @@
+package dynamodb
+
+import (
+	"fmt"
+	"github.com/cyberdelia/dynamodb/types"
+	"strings"
+)
+
+// Only return the attributes at the given document paths. Paths are
+// attribute names, optionally followed by map keys and list indexes, as in
+// "authors[0]" or "address.city". Iterators also return the keys of the
+// table and index, to resume from the current item.
+func Project(paths ...string) Option {
+	return func(o *options) {
+		if len(paths) == 0 {
+			return
+		}
+		o.paths = paths
+	}
+}
+
+// Only return the attributes of the given struct.
+func ProjectStruct(v interface{}) Option {
+	return func(o *options) {
+		attrs, err := types.Attributes(v)
+		if err != nil {
+			o.err = err
+			return
+		}
+		Project(attrs...)(o)
+	}
+}
+
+// projectKeys adds the given key attributes missing from the projection,
+// if any, for iterators to resume from the keys of their current item.
+func (o *options) projectKeys(keys []string) {
+	if len(o.paths) == 0 || o.err != nil {
+		return
+	}
+	paths := append([]string(nil), o.paths...)
+	for _, key := range keys {
+		found := false
+		for _, path := range o.paths {
+			found = found || path == key
+		}
+		if !found {
+			paths = append(paths, key)
+		}
+	}
+	if len(paths) > len(o.paths) {
+		o.paths = paths
+		o.projection, o.err = projection(paths, o.names)
+	}
+}
+
+// projection builds a projection expression out of the given document
+// paths, adding a placeholder for each attribute name to names, without
+// replacing those already there.
+func projection(paths []string, names map[string]string) (string, error) {
+	placeholders := make(map[string]string)
+	for placeholder, name := range names {
+		placeholders[name] = placeholder
+	}
+	n := 0
+	expressions := make([]string, len(paths))
+	for i, path := range paths {
+		var expression []string
+		for _, element := range strings.Split(path, ".") {
+			name, indexes := element, ""
+			if j := strings.IndexByte(element, '['); j >= 0 {
+				name, indexes = element[:j], element[j:]
+			}
+			if name == "" || strings.ContainsRune(name, ']') || !validIndexes(indexes) {
+				return "", fmt.Errorf("dynamodb: invalid path %q", path)
+			}
+			placeholder, present := placeholders[name]
+			for !present {
+				placeholder = fmt.Sprintf("#p%d", n)
+				n++
+				if _, taken := names[placeholder]; !taken {
+					placeholders[name] = placeholder
+					names[placeholder] = name
+					present = true
+				}
+			}
+			expression = append(expression, placeholder+indexes)
+		}
+		expressions[i] = strings.Join(expression, ".")
+	}
+	return strings.Join(expressions, ", "), nil
+}
+
+// validIndexes reports whether s is a sequence of list indexes, as in
+// "[0][12]".
+func validIndexes(s string) bool {
+	for s != "" {
+		i := strings.IndexByte(s, ']')
+		if s[0] != '[' || i < 2 {
+			return false
+		}
+		for _, c := range s[1:i] {
+			if c < '0' || c > '9' {
+				return false
+			}
+		}
+		s = s[i+1:]
+	}
+	return true
+}
+
+// referenced returns the names of the given ones whose placeholder appears
+// in one of the given expressions.
+func referenced(names map[string]string, expressions ...string) map[string]string {
+	var m map[string]string
+	for _, expression := range expressions {
+		for i := strings.IndexByte(expression, '#'); i >= 0; i = strings.IndexByte(expression, '#') {
+			expression = expression[i:]
+			j := 1
+			for j < len(expression) && isPlaceholderByte(expression[j]) {
+				j++
+			}
+			if name, present := names[expression[:j]]; present {
+				if m == nil {
+					m = make(map[string]string)
+				}
+				m[expression[:j]] = name
+			}
+			expression = expression[j:]
+		}
+	}
+	return m
+}
+
+func isPlaceholderByte(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
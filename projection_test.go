@@ -0,0 +1,124 @@
+package dynamodb
+
+import (
+	"github.com/cyberdelia/dynamodb/types"
+	"reflect"
+	"testing"
+)
+
+var projectionTests = []struct {
+	paths      []string
+	expression string
+	names      map[string]string
+}{
+	{
+		[]string{"title", "year"},
+		"#p0, #p1",
+		map[string]string{"#p0": "title", "#p1": "year"},
+	},
+	{
+		[]string{"authors[0]", "address.city", "address.zip[1][2]"},
+		"#p0[0], #p1.#p2, #p1.#p3[1][2]",
+		map[string]string{"#p0": "authors", "#p1": "address", "#p2": "city", "#p3": "zip"},
+	},
+}
+
+func TestProjection(t *testing.T) {
+	for _, e := range projectionTests {
+		names := make(map[string]string)
+		expression, err := projection(e.paths, names)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expression != e.expression {
+			t.Errorf("got %v wants %v", expression, e.expression)
+		}
+		if !reflect.DeepEqual(names, e.names) {
+			t.Errorf("got %v wants %v", names, e.names)
+		}
+	}
+}
+
+func TestInvalidProjection(t *testing.T) {
+	for _, path := range []string{"", "a..b", "[0]", "a[b]", "a[]", "a[0", "a]"} {
+		if _, err := projection([]string{path}, make(map[string]string)); err == nil {
+			t.Errorf("path %q wasn't rejected", path)
+		}
+	}
+}
+
+func TestPluckProjection(t *testing.T) {
+	var body map[string]interface{}
+	s := testService(func(action string, b map[string]interface{}) (int, interface{}) {
+		body = b
+		return 200, map[string]interface{}{
+			"Items": []interface{}{
+				map[string]interface{}{"year": map[string]string{"N": "2007"}},
+			},
+		}
+	})
+	items, err := s.Pluck("papers", &paper{}, "year")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if papers := items.([]*paper); len(papers) != 1 || papers[0].Year != 2007 {
+		t.Errorf("got %v", papers)
+	}
+	if body["ProjectionExpression"] != "#p0, #p1" {
+		t.Errorf("got %v", body)
+	}
+	names := map[string]interface{}{"#p0": "year", "#p1": "title"}
+	if !reflect.DeepEqual(body["ExpressionAttributeNames"], names) {
+		t.Errorf("got %v wants %v", body["ExpressionAttributeNames"], names)
+	}
+}
+
+func TestProjectionPlaceholders(t *testing.T) {
+	filter := Filter("#p1 > :y", map[string]string{"#p1": "year"}, types.AttributeValue{":y": {"N": "2000"}})
+	control := map[string]string{"#p0": "title", "#p1": "year", "#p2": "score"}
+	for _, opts := range [][]Option{
+		{filter, Project("title", "year", "score")},
+		{Project("title", "year", "score"), filter},
+	} {
+		o := newOptions(opts)
+		if o.err != nil {
+			t.Fatal(o.err)
+		}
+		if o.projection != "#p0, #p1, #p2" {
+			t.Errorf("got %v wants %v", o.projection, "#p0, #p1, #p2")
+		}
+		if !reflect.DeepEqual(o.names, control) {
+			t.Errorf("got %v wants %v", o.names, control)
+		}
+	}
+}
+
+func TestGetReferencedNames(t *testing.T) {
+	var body map[string]interface{}
+	s := testService(func(action string, b map[string]interface{}) (int, interface{}) {
+		body = b
+		return 200, map[string]interface{}{}
+	})
+	filter := Filter("#y > :y", map[string]string{"#y": "year"}, types.AttributeValue{":y": {"N": "2000"}})
+	if err := s.Get("papers", &paper{Title: "Dynamo", Year: 2007}, filter); err != nil {
+		t.Fatal(err)
+	}
+	if _, present := body["ExpressionAttributeNames"]; present {
+		t.Errorf("got %v", body)
+	}
+	if err := s.Get("papers", &paper{Title: "Dynamo", Year: 2007}, filter, Project("title")); err != nil {
+		t.Fatal(err)
+	}
+	names := map[string]interface{}{"#p0": "title"}
+	if !reflect.DeepEqual(body["ExpressionAttributeNames"], names) {
+		t.Errorf("got %v wants %v", body["ExpressionAttributeNames"], names)
+	}
+}
+
+func TestReferenced(t *testing.T) {
+	names := map[string]string{"#p1": "year", "#p10": "title", "#a_b": "score", "#c": "venue"}
+	control := map[string]string{"#p10": "title", "#a_b": "score"}
+	if got := referenced(names, "#p10[0].#a_b", "", "#d"); !reflect.DeepEqual(got, control) {
+		t.Errorf("got %v wants %v", got, control)
+	}
+}
@@ -0,0 +1,212 @@
+package streams
+
+import (
+	"context"
+	"github.com/cyberdelia/dynamodb"
+	"sync"
+	"time"
+)
+
+// A Checkpointer stores the sequence number of the last record processed
+// in each shard, for consumers to resume where they stopped.
+type Checkpointer interface {
+	// Returns the last sequence number stored for the given shard, or an
+	// empty string when there is none.
+	Get(ctx context.Context, streamArn, shardId string) (string, error)
+	// Stores the given sequence number for the given shard.
+	Set(ctx context.Context, streamArn, shardId, sequenceNumber string) error
+}
+
+// MemoryCheckpointer stores sequence numbers in memory.
+type MemoryCheckpointer struct {
+	mu        sync.Mutex
+	sequences map[string]string
+}
+
+func (m *MemoryCheckpointer) Get(ctx context.Context, streamArn, shardId string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.sequences[streamArn+"/"+shardId], nil
+}
+
+func (m *MemoryCheckpointer) Set(ctx context.Context, streamArn, shardId, sequenceNumber string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.sequences == nil {
+		m.sequences = make(map[string]string)
+	}
+	m.sequences[streamArn+"/"+shardId] = sequenceNumber
+	return nil
+}
+
+// A Handler processes records read from a shard, in order. An error stops
+// the consumer, whose next run reads the records again from the last
+// checkpoint.
+type Handler func(ctx context.Context, shard Shard, records []Record) error
+
+// A Consumer reads every shard of a stream, child shards being read once
+// their parent is read entirely, checkpointing after each batch of records
+// successfully handled.
+type Consumer struct {
+	// Service reading the stream, DefaultService when nil.
+	Service   *Service
+	StreamArn string
+	// Checkpoints of shards, kept in memory when nil.
+	Checkpoints Checkpointer
+	// Position of shards without checkpoint, TrimHorizon when empty. Child
+	// shards of shards read by the consumer always start at TrimHorizon.
+	IteratorType string
+	// Maximum number of records handled at once.
+	Limit int
+	// Delay between reads of a shard without new records, and between
+	// refreshes of the shards of the stream, a second when zero.
+	PollInterval time.Duration
+}
+
+// Returns a consumer of the given stream, checkpointing in memory.
+func NewConsumer(streamArn string) *Consumer {
+	return &Consumer{
+		Service:      DefaultService,
+		StreamArn:    streamArn,
+		Checkpoints:  &MemoryCheckpointer{},
+		IteratorType: TrimHorizon,
+		PollInterval: time.Second,
+	}
+}
+
+// Reads the stream until the context is canceled, or until the handler
+// returns an error, which is returned.
+func (c *Consumer) Run(ctx context.Context, h Handler) error {
+	if c.Service == nil {
+		c.Service = DefaultService
+	}
+	if c.Checkpoints == nil {
+		c.Checkpoints = &MemoryCheckpointer{}
+	}
+	if c.PollInterval <= 0 {
+		c.PollInterval = time.Second
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		shard string
+		err   error
+	}
+	results := make(chan result)
+	running := make(map[string]bool)
+	done := make(map[string]bool)
+	wait := func() {
+		for range running {
+			<-results
+		}
+	}
+
+	ticker := time.NewTicker(c.PollInterval)
+	defer ticker.Stop()
+	for {
+		description, err := c.Service.DescribeStream(ctx, c.StreamArn)
+		if err != nil {
+			cancel()
+			wait()
+			return err
+		}
+		shards := make(map[string]bool)
+		for _, shard := range description.Shards {
+			shards[shard.ShardId] = true
+		}
+		for _, shard := range description.Shards {
+			parent := shard.ParentShardId
+			if running[shard.ShardId] || done[shard.ShardId] || (shards[parent] && !done[parent]) {
+				continue
+			}
+			running[shard.ShardId] = true
+			// Records of a child shard follow those of its parent.
+			child := done[parent]
+			go func(shard Shard) {
+				results <- result{shard.ShardId, c.consume(ctx, shard, child, h)}
+			}(shard)
+		}
+
+		refresh := false
+		for !refresh {
+			select {
+			case r := <-results:
+				delete(running, r.shard)
+				if r.err != nil {
+					cancel()
+					wait()
+					return r.err
+				}
+				done[r.shard] = true
+				// Children of the shard can now be read
+				refresh = true
+			case <-ticker.C:
+				refresh = true
+			case <-ctx.Done():
+				wait()
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+// consume reads the given shard until it's closed and read entirely.
+func (c *Consumer) consume(ctx context.Context, shard Shard, child bool, h Handler) error {
+	iterator, err := c.iterator(ctx, shard, child)
+	if err != nil {
+		return err
+	}
+	for iterator != "" {
+		records, next, err := c.Service.GetRecords(ctx, iterator, c.Limit)
+		if dynamodb.IsError(err, "ExpiredIteratorException") {
+			if iterator, err = c.iterator(ctx, shard, child); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if len(records) > 0 {
+			for i := range records {
+				if records[i].EventSourceARN == "" {
+					records[i].EventSourceARN = c.StreamArn
+				}
+			}
+			if err := h(ctx, shard, records); err != nil {
+				return err
+			}
+			sequence := records[len(records)-1].DynamoDB.SequenceNumber
+			if err := c.Checkpoints.Set(ctx, c.StreamArn, shard.ShardId, sequence); err != nil {
+				return err
+			}
+		} else if next != "" {
+			select {
+			case <-time.After(c.PollInterval):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		iterator = next
+	}
+	return nil
+}
+
+// iterator returns an iterator of the given shard positioned after its
+// checkpoint, or at its start for children of shards read entirely.
+func (c *Consumer) iterator(ctx context.Context, shard Shard, child bool) (string, error) {
+	sequence, err := c.Checkpoints.Get(ctx, c.StreamArn, shard.ShardId)
+	if err != nil {
+		return "", err
+	}
+	if sequence != "" {
+		return c.Service.GetShardIterator(ctx, c.StreamArn, shard.ShardId, AfterSequenceNumber, sequence)
+	}
+	position := c.IteratorType
+	if position == "" || child {
+		position = TrimHorizon
+	}
+	return c.Service.GetShardIterator(ctx, c.StreamArn, shard.ShardId, position, "")
+}
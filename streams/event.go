@@ -0,0 +1,47 @@
+package streams
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// An Event is the payload delivered to Lambda functions triggered by a
+// DynamoDB stream.
+type Event struct {
+	Records []Record `json:"Records"`
+}
+
+// Decodes the given Lambda event payload.
+func ParseEvent(b []byte) (*Event, error) {
+	var e Event
+	if err := json.Unmarshal(b, &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// Dispatch the records of the event with the given registry, reporting
+// the first record that failed, if any, for Lambda to retry it along with
+// the following records. The response is to be returned without error, as
+// Lambda retries the whole batch of functions returning an error.
+func (e *Event) Process(ctx context.Context, r *Registry) *BatchResponse {
+	response := &BatchResponse{
+		BatchItemFailures: []BatchItemFailure{},
+	}
+	if i, err := r.dispatch(ctx, e.Records); err != nil {
+		response.BatchItemFailures = append(response.BatchItemFailures, BatchItemFailure{
+			ItemIdentifier: e.Records[i].DynamoDB.SequenceNumber,
+		})
+	}
+	return response
+}
+
+// A BatchResponse reports records a Lambda function failed to process, to
+// be returned when the event source mapping reports batch item failures.
+type BatchResponse struct {
+	BatchItemFailures []BatchItemFailure `json:"batchItemFailures"`
+}
+
+type BatchItemFailure struct {
+	ItemIdentifier string `json:"itemIdentifier"`
+}
@@ -0,0 +1,151 @@
+package streams
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A Change is a record decoded into values of the type registered for its
+// table.
+type Change[T any] struct {
+	Record *Record
+	// The item before the change, nil when inserted or when the stream
+	// doesn't carry old images.
+	Before *T
+	// The item after the change, nil when removed or when the stream
+	// doesn't carry new images.
+	After *T
+	// Names of the attributes added, removed or modified by the change.
+	Changed []string
+}
+
+// A Registry dispatches records to the handlers registered for their
+// table, retrying records whose handler fails.
+type Registry struct {
+	// Number of times a failing record is retried.
+	Retries int
+	// Delay before the first retry, doubled on each retry.
+	Backoff time.Duration
+	// Called with records that can't be decoded or whose handler still
+	// fails after retries. The record is skipped unless it returns an
+	// error, which stops processing; without it, the error is returned.
+	Poison func(ctx context.Context, r *Record, err error) error
+
+	handlers map[string]func(context.Context, *Record) (bool, error)
+}
+
+// Returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		handlers: make(map[string]func(context.Context, *Record) (bool, error)),
+	}
+}
+
+// Register the given handler for changes of the given table, decoding
+// images into values of type T.
+func On[T any](r *Registry, tableName string, fn func(ctx context.Context, c *Change[T]) error) {
+	if r.handlers == nil {
+		r.handlers = make(map[string]func(context.Context, *Record) (bool, error))
+	}
+	r.handlers[tableName] = func(ctx context.Context, record *Record) (bool, error) {
+		change := &Change[T]{
+			Record:  record,
+			Changed: changed(record),
+		}
+		if record.DynamoDB.OldImage != nil && record.EventName != Insert {
+			change.Before = new(T)
+			if err := record.UnmarshalOldImage(change.Before); err != nil {
+				return false, err
+			}
+		}
+		if record.DynamoDB.NewImage != nil && record.EventName != Remove {
+			change.After = new(T)
+			if err := record.UnmarshalNewImage(change.After); err != nil {
+				return false, err
+			}
+		}
+		return true, fn(ctx, change)
+	}
+}
+
+// Dispatch the given records, in order, to the handlers registered for
+// their table, identified by their event source. Records of other tables
+// are ignored.
+func (r *Registry) Process(ctx context.Context, records []Record) error {
+	_, err := r.dispatch(ctx, records)
+	return err
+}
+
+// dispatch returns the index of the record whose processing failed along
+// with the error.
+func (r *Registry) dispatch(ctx context.Context, records []Record) (int, error) {
+	for i := range records {
+		record := &records[i]
+		handler, present := r.handlers[tableName(record.EventSourceARN)]
+		if !present {
+			continue
+		}
+		if err := r.process(ctx, handler, record); err != nil {
+			return i, err
+		}
+	}
+	return len(records), nil
+}
+
+// Returns a consumer handler dispatching records with this registry.
+func (r *Registry) Handler() Handler {
+	return func(ctx context.Context, shard Shard, records []Record) error {
+		return r.Process(ctx, records)
+	}
+}
+
+func (r *Registry) process(ctx context.Context, handler func(context.Context, *Record) (bool, error), record *Record) error {
+	delay := r.Backoff
+	decoded, err := handler(ctx, record)
+	for i := 0; decoded && err != nil && i < r.Retries; i++ {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		delay *= 2
+		decoded, err = handler(ctx, record)
+	}
+	if err == nil || r.Poison == nil {
+		return err
+	}
+	return r.Poison(ctx, record, err)
+}
+
+// changed returns the sorted names of the attributes differing between
+// the old and new images of the given record.
+func changed(record *Record) []string {
+	before, after := record.DynamoDB.OldImage, record.DynamoDB.NewImage
+	var names []string
+	for name, value := range after {
+		if old, present := before[name]; !present || !reflect.DeepEqual(old, value) {
+			names = append(names, name)
+		}
+	}
+	for name := range before {
+		if _, present := after[name]; !present {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// tableName returns the name of the table of the given stream ARN, as in
+// "arn:aws:dynamodb:us-east-1:123456789012:table/papers/stream/label".
+func tableName(arn string) string {
+	_, name, found := strings.Cut(arn, ":table/")
+	if !found {
+		return ""
+	}
+	name, _, _ = strings.Cut(name, "/")
+	return name
+}
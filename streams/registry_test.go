@@ -0,0 +1,168 @@
+package streams
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const arn = "arn:aws:dynamodb:us-east-1:123456789012:table/papers/stream/2015-06-27T00:48:05.899"
+
+func TestTableName(t *testing.T) {
+	if name := tableName(arn); name != "papers" {
+		t.Errorf("unexpected table name %q", name)
+	}
+	if name := tableName("arn:aws:dynamodb:us-east-1:123456789012:table/papers"); name != "papers" {
+		t.Errorf("unexpected table name %q", name)
+	}
+	if name := tableName(""); name != "" {
+		t.Errorf("unexpected table name %q", name)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	var r Record
+	if err := json.Unmarshal([]byte(record), &r); err != nil {
+		t.Fatal(err)
+	}
+	r.EventSourceARN = arn
+	insert := r
+	insert.EventName = Insert
+	insert.DynamoDB.OldImage = nil
+	remove := r
+	remove.EventName = Remove
+	remove.DynamoDB.NewImage = nil
+	other := r
+	other.EventSourceARN = "arn:aws:dynamodb:us-east-1:123456789012:table/books/stream/label"
+
+	registry := NewRegistry()
+	var changes []*Change[paper]
+	On(registry, "papers", func(ctx context.Context, c *Change[paper]) error {
+		changes = append(changes, c)
+		return nil
+	})
+	if err := registry.Process(context.Background(), []Record{insert, r, remove, other}); err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 3 {
+		t.Fatalf("expected 3 changes, got %d", len(changes))
+	}
+	if c := changes[0]; c.Before != nil || c.After.Year != 2001 || !reflect.DeepEqual(c.Changed, []string{"title", "year"}) {
+		t.Errorf("unexpected insert %+v", c)
+	}
+	if c := changes[1]; c.Before.Year != 2000 || c.After.Year != 2001 || !reflect.DeepEqual(c.Changed, []string{"year"}) {
+		t.Errorf("unexpected modify %+v", c)
+	}
+	if c := changes[2]; c.Before.Year != 2000 || c.After != nil || !reflect.DeepEqual(c.Changed, []string{"title", "year"}) {
+		t.Errorf("unexpected remove %+v", c)
+	}
+}
+
+func TestRegistryRetries(t *testing.T) {
+	var r Record
+	if err := json.Unmarshal([]byte(record), &r); err != nil {
+		t.Fatal(err)
+	}
+	r.EventSourceARN = arn
+	failure := errors.New("failure")
+
+	registry := &Registry{Retries: 2}
+	calls := 0
+	On(registry, "papers", func(ctx context.Context, c *Change[paper]) error {
+		calls++
+		return failure
+	})
+	if err := registry.Process(context.Background(), []Record{r}); err != failure {
+		t.Errorf("expected failure, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 calls, got %d", calls)
+	}
+
+	var poisoned []*Record
+	registry.Poison = func(ctx context.Context, r *Record, err error) error {
+		poisoned = append(poisoned, r)
+		return nil
+	}
+	if err := registry.Process(context.Background(), []Record{r, r}); err != nil {
+		t.Errorf("expected poison records to be skipped, got %v", err)
+	}
+	if len(poisoned) != 2 {
+		t.Errorf("expected 2 poison records, got %d", len(poisoned))
+	}
+}
+
+const event = `{
+	"Records": [
+		{
+			"eventID": "1",
+			"eventName": "INSERT",
+			"eventSource": "aws:dynamodb",
+			"eventSourceARN": "arn:aws:dynamodb:us-east-1:123456789012:table/papers/stream/2015-06-27T00:48:05.899",
+			"dynamodb": {
+				"Keys": {"title": {"S": "a"}},
+				"NewImage": {"title": {"S": "a"}, "year": {"N": "2000"}, "reviews": {"L": [{"M": {"score": {"N": "4"}, "accepted": {"BOOL": true}}}]}, "abstract": {"NULL": true}},
+				"SequenceNumber": "100",
+				"StreamViewType": "NEW_AND_OLD_IMAGES"
+			}
+		},
+		{
+			"eventID": "2",
+			"eventName": "REMOVE",
+			"eventSource": "aws:dynamodb",
+			"eventSourceARN": "arn:aws:dynamodb:us-east-1:123456789012:table/papers/stream/2015-06-27T00:48:05.899",
+			"dynamodb": {
+				"Keys": {"title": {"S": "b"}},
+				"OldImage": {"title": {"S": "b"}, "year": {"N": "2001"}},
+				"SequenceNumber": "200",
+				"StreamViewType": "NEW_AND_OLD_IMAGES"
+			}
+		}
+	]
+}`
+
+type review struct {
+	Score    int  `dynamo:"score"`
+	Accepted bool `dynamo:"accepted"`
+}
+
+type reviewedPaper struct {
+	Title    string   `dynamo:"title,hash"`
+	Year     int      `dynamo:"year"`
+	Abstract string   `dynamo:"abstract"`
+	Reviews  []review `dynamo:"reviews"`
+}
+
+func TestEvent(t *testing.T) {
+	e, err := ParseEvent([]byte(event))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(e.Records) != 2 {
+		t.Fatalf("got %v wants %v", len(e.Records), 2)
+	}
+	var p reviewedPaper
+	if err := e.Records[0].UnmarshalNewImage(&p); err != nil {
+		t.Fatal(err)
+	}
+	expected := reviewedPaper{Title: "a", Year: 2000, Reviews: []review{{Score: 4, Accepted: true}}}
+	if !reflect.DeepEqual(p, expected) {
+		t.Errorf("got %v wants %v", p, expected)
+	}
+
+	failure := errors.New("failure")
+	registry := NewRegistry()
+	On(registry, "papers", func(ctx context.Context, c *Change[reviewedPaper]) error {
+		if c.Record.EventName == Remove {
+			return failure
+		}
+		return nil
+	})
+	response := e.Process(context.Background(), registry)
+	control := []BatchItemFailure{{ItemIdentifier: "200"}}
+	if !reflect.DeepEqual(response.BatchItemFailures, control) {
+		t.Errorf("got %v wants %v", response.BatchItemFailures, control)
+	}
+}
@@ -0,0 +1,308 @@
+// A client for DynamoDB Streams
+//
+// This package reads the changes made to tables whose stream is enabled.
+// Images of the items carried by stream records are decoded into structs
+// with the same tags as the dynamodb package.
+package streams
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/cyberdelia/dynamodb"
+	"github.com/cyberdelia/dynamodb/credentials"
+	"github.com/cyberdelia/dynamodb/types"
+	"net/http"
+	"strings"
+)
+
+var (
+	ErrNoImage = errors.New("dynamodb: record has no such image")
+)
+
+// Shard iterator types accepted by GetShardIterator.
+const (
+	TrimHorizon         = "TRIM_HORIZON"
+	Latest              = "LATEST"
+	AtSequenceNumber    = "AT_SEQUENCE_NUMBER"
+	AfterSequenceNumber = "AFTER_SEQUENCE_NUMBER"
+)
+
+// Event names of records.
+const (
+	Insert = "INSERT"
+	Modify = "MODIFY"
+	Remove = "REMOVE"
+)
+
+var (
+	DefaultService = &Service{
+		Region:  "us-east-1",
+		Version: "20120810",
+		Client:  credentials.DefaultSigner,
+	}
+)
+
+type Service struct {
+	Region  string
+	Version string
+	Client  dynamodb.Doer
+
+	// Middleware wraps every action performed, the first one being the
+	// outermost.
+	Middleware []dynamodb.Middleware
+}
+
+type Stream struct {
+	StreamArn   string
+	StreamLabel string
+	TableName   string
+}
+
+type StreamDescription struct {
+	CreationRequestDateTime types.Timestamp
+	KeySchema               types.KeySchema
+	Shards                  []Shard
+	StreamArn               string
+	StreamLabel             string
+	StreamStatus            string
+	StreamViewType          string
+	TableName               string
+}
+
+type Shard struct {
+	ParentShardId       string
+	SequenceNumberRange SequenceNumberRange
+	ShardId             string
+}
+
+type SequenceNumberRange struct {
+	EndingSequenceNumber   string
+	StartingSequenceNumber string
+}
+
+// A Record describes a change of an item, as returned by GetRecords or
+// delivered to Lambda functions. Consumers set the event source of records
+// to the ARN of their stream.
+type Record struct {
+	AwsRegion      string        `json:"awsRegion"`
+	DynamoDB       StreamRecord  `json:"dynamodb"`
+	EventID        string        `json:"eventID"`
+	EventName      string        `json:"eventName"`
+	EventSource    string        `json:"eventSource"`
+	EventSourceARN string        `json:"eventSourceARN,omitempty"`
+	EventVersion   string        `json:"eventVersion"`
+	UserIdentity   *UserIdentity `json:"userIdentity,omitempty"`
+}
+
+type StreamRecord struct {
+	ApproximateCreationDateTime types.Timestamp
+	Keys                        types.AttributeValue
+	NewImage                    types.AttributeValue `json:",omitempty"`
+	OldImage                    types.AttributeValue `json:",omitempty"`
+	SequenceNumber              string
+	SizeBytes                   int
+	StreamViewType              string
+}
+
+// UserIdentity identifies the time to live service for items it removed.
+type UserIdentity struct {
+	PrincipalId string `json:"principalId"`
+	Type        string `json:"type"`
+}
+
+// Unmarshal the keys of the changed item into the given struct.
+func (r *Record) UnmarshalKeys(v interface{}) error {
+	return unmarshal(r.DynamoDB.Keys, v)
+}
+
+// Unmarshal the item as it was after the change into the given struct.
+func (r *Record) UnmarshalNewImage(v interface{}) error {
+	return unmarshal(r.DynamoDB.NewImage, v)
+}
+
+// Unmarshal the item as it was before the change into the given struct.
+func (r *Record) UnmarshalOldImage(v interface{}) error {
+	return unmarshal(r.DynamoDB.OldImage, v)
+}
+
+func unmarshal(a types.AttributeValue, v interface{}) error {
+	if a == nil {
+		return ErrNoImage
+	}
+	return types.Unmarshal(a, v)
+}
+
+func (s *Service) Do(ctx context.Context, action string, body interface{}, a interface{}) error {
+	return dynamodb.Chain(s.do, s.Middleware...)(ctx, action, body, a)
+}
+
+func (s *Service) do(ctx context.Context, action string, body interface{}, a interface{}) error {
+	url := fmt.Sprintf("https://streams.dynamodb.%s.amazonaws.com/", s.Region)
+
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	r, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(b))
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Content-Type", "application/x-amz-json-1.0")
+	r.Header.Set("X-Amz-Target", fmt.Sprintf("DynamoDBStreams_%s.%s", s.Version, action))
+
+	resp, err := s.Client.Do(r)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if status := resp.StatusCode; status != 200 {
+		var e struct {
+			Message string
+			Type    string `json:"__type"`
+		}
+		json.NewDecoder(resp.Body).Decode(&e)
+		if i := strings.LastIndex(e.Type, "#"); i >= 0 {
+			e.Type = e.Type[i+1:]
+		}
+		return &dynamodb.Error{
+			StatusCode: status,
+			Type:       e.Type,
+			Message:    e.Message,
+		}
+	}
+
+	if a == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(a)
+}
+
+// List streams of the given table, or of every table when empty.
+func (s *Service) ListStreams(ctx context.Context, tableName string) ([]Stream, error) {
+	var streams []Stream
+	var start string
+	for {
+		var resp struct {
+			Streams                []Stream
+			LastEvaluatedStreamArn string
+		}
+		body := struct {
+			TableName               string `json:",omitempty"`
+			ExclusiveStartStreamArn string `json:",omitempty"`
+		}{
+			TableName:               tableName,
+			ExclusiveStartStreamArn: start,
+		}
+		if err := s.Do(ctx, "ListStreams", body, &resp); err != nil {
+			return nil, err
+		}
+		streams = append(streams, resp.Streams...)
+		if resp.LastEvaluatedStreamArn == "" {
+			return streams, nil
+		}
+		start = resp.LastEvaluatedStreamArn
+	}
+}
+
+// List streams of the given table, or of every table when empty.
+func ListStreams(ctx context.Context, tableName string) ([]Stream, error) {
+	return DefaultService.ListStreams(ctx, tableName)
+}
+
+// Describe the given stream, along with all its shards.
+func (s *Service) DescribeStream(ctx context.Context, streamArn string) (StreamDescription, error) {
+	var description StreamDescription
+	var shards []Shard
+	var start string
+	for {
+		var resp struct {
+			StreamDescription struct {
+				StreamDescription
+				LastEvaluatedShardId string
+			}
+		}
+		body := struct {
+			StreamArn             string
+			ExclusiveStartShardId string `json:",omitempty"`
+		}{
+			StreamArn:             streamArn,
+			ExclusiveStartShardId: start,
+		}
+		if err := s.Do(ctx, "DescribeStream", body, &resp); err != nil {
+			return StreamDescription{}, err
+		}
+		description = resp.StreamDescription.StreamDescription
+		shards = append(shards, description.Shards...)
+		if resp.StreamDescription.LastEvaluatedShardId == "" {
+			description.Shards = shards
+			return description, nil
+		}
+		start = resp.StreamDescription.LastEvaluatedShardId
+	}
+}
+
+// Describe the given stream, along with all its shards.
+func DescribeStream(ctx context.Context, streamArn string) (StreamDescription, error) {
+	return DefaultService.DescribeStream(ctx, streamArn)
+}
+
+// Returns an iterator reading the given shard from the position described
+// by the iterator type, and the sequence number for AtSequenceNumber and
+// AfterSequenceNumber.
+func (s *Service) GetShardIterator(ctx context.Context, streamArn, shardId, iteratorType, sequenceNumber string) (string, error) {
+	var resp struct {
+		ShardIterator string
+	}
+	body := struct {
+		StreamArn         string
+		ShardId           string
+		ShardIteratorType string
+		SequenceNumber    string `json:",omitempty"`
+	}{
+		StreamArn:         streamArn,
+		ShardId:           shardId,
+		ShardIteratorType: iteratorType,
+		SequenceNumber:    sequenceNumber,
+	}
+	if err := s.Do(ctx, "GetShardIterator", body, &resp); err != nil {
+		return "", err
+	}
+	return resp.ShardIterator, nil
+}
+
+// Returns an iterator reading the given shard.
+func GetShardIterator(ctx context.Context, streamArn, shardId, iteratorType, sequenceNumber string) (string, error) {
+	return DefaultService.GetShardIterator(ctx, streamArn, shardId, iteratorType, sequenceNumber)
+}
+
+// Returns at most limit records, or as many as DynamoDB returns when zero,
+// from the given shard iterator, and the iterator of the next records,
+// empty once the shard is closed and read entirely.
+func (s *Service) GetRecords(ctx context.Context, iterator string, limit int) ([]Record, string, error) {
+	var resp struct {
+		Records           []Record
+		NextShardIterator string
+	}
+	body := struct {
+		ShardIterator string
+		Limit         int `json:",omitempty"`
+	}{
+		ShardIterator: iterator,
+		Limit:         limit,
+	}
+	if err := s.Do(ctx, "GetRecords", body, &resp); err != nil {
+		return nil, "", err
+	}
+	return resp.Records, resp.NextShardIterator, nil
+}
+
+// Returns at most limit records from the given shard iterator.
+func GetRecords(ctx context.Context, iterator string, limit int) ([]Record, string, error) {
+	return DefaultService.GetRecords(ctx, iterator, limit)
+}
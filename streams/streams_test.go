@@ -0,0 +1,225 @@
+package streams
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/cyberdelia/dynamodb/dynamodbtest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Returns a service answering requests with the given function.
+func testService(fn func(action string, body map[string]interface{}) (int, interface{})) *Service {
+	return &Service{
+		Region:  "us-east-1",
+		Version: "20120810",
+		Client:  dynamodbtest.Client(fn),
+	}
+}
+
+type paper struct {
+	Title string `dynamo:"title,hash"`
+	Year  int    `dynamo:"year"`
+}
+
+const record = `{
+	"eventID": "1",
+	"eventName": "MODIFY",
+	"eventSource": "aws:dynamodb",
+	"eventVersion": "1.1",
+	"awsRegion": "us-east-1",
+	"dynamodb": {
+		"ApproximateCreationDateTime": 1479499740,
+		"Keys": {"title": {"S": "a"}},
+		"NewImage": {"title": {"S": "a"}, "year": {"N": "2001"}},
+		"OldImage": {"title": {"S": "a"}, "year": {"N": "2000"}},
+		"SequenceNumber": "100",
+		"SizeBytes": 26,
+		"StreamViewType": "NEW_AND_OLD_IMAGES"
+	}
+}`
+
+func TestRecord(t *testing.T) {
+	var r Record
+	if err := json.Unmarshal([]byte(record), &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.EventName != Modify || r.DynamoDB.SequenceNumber != "100" {
+		t.Errorf("unexpected record %+v", r)
+	}
+	if !r.DynamoDB.ApproximateCreationDateTime.Equal(time.Unix(1479499740, 0)) {
+		t.Errorf("unexpected creation time %v", r.DynamoDB.ApproximateCreationDateTime)
+	}
+	var keys, before, after paper
+	if err := r.UnmarshalKeys(&keys); err != nil || keys.Title != "a" {
+		t.Errorf("unexpected keys %+v (%v)", keys, err)
+	}
+	if err := r.UnmarshalOldImage(&before); err != nil || before.Year != 2000 {
+		t.Errorf("unexpected old image %+v (%v)", before, err)
+	}
+	if err := r.UnmarshalNewImage(&after); err != nil || after.Year != 2001 {
+		t.Errorf("unexpected new image %+v (%v)", after, err)
+	}
+	r.DynamoDB.OldImage = nil
+	if err := r.UnmarshalOldImage(&before); err != ErrNoImage {
+		t.Errorf("expected ErrNoImage, got %v", err)
+	}
+}
+
+func TestDescribeStream(t *testing.T) {
+	s := testService(func(action string, body map[string]interface{}) (int, interface{}) {
+		if action != "DescribeStream" {
+			t.Fatalf("unexpected action %s", action)
+		}
+		shard, next := "shard-1", "shard-1"
+		if body["ExclusiveStartShardId"] == "shard-1" {
+			shard, next = "shard-2", ""
+		}
+		return 200, map[string]interface{}{
+			"StreamDescription": map[string]interface{}{
+				"StreamArn":            "arn",
+				"StreamStatus":         "ENABLED",
+				"Shards":               []interface{}{map[string]string{"ShardId": shard}},
+				"LastEvaluatedShardId": next,
+			},
+		}
+	})
+	description, err := s.DescribeStream(context.Background(), "arn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if description.StreamStatus != "ENABLED" || len(description.Shards) != 2 || description.Shards[1].ShardId != "shard-2" {
+		t.Errorf("unexpected description %+v", description)
+	}
+}
+
+func TestConsumer(t *testing.T) {
+	var r Record
+	if err := json.Unmarshal([]byte(record), &r); err != nil {
+		t.Fatal(err)
+	}
+	records := map[string][]Record{
+		"parent": {r},
+		"child":  {r, r},
+	}
+	records["child"][1].DynamoDB.SequenceNumber = "300"
+	records["child"][0].DynamoDB.SequenceNumber = "200"
+
+	var mu sync.Mutex
+	var requests []string
+	s := testService(func(action string, body map[string]interface{}) (int, interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch action {
+		case "DescribeStream":
+			return 200, map[string]interface{}{
+				"StreamDescription": map[string]interface{}{
+					"Shards": []interface{}{
+						map[string]string{"ShardId": "child", "ParentShardId": "parent"},
+						map[string]string{"ShardId": "parent", "ParentShardId": "trimmed"},
+					},
+				},
+			}
+		case "GetShardIterator":
+			requests = append(requests, body["ShardId"].(string)+" "+body["ShardIteratorType"].(string))
+			return 200, map[string]string{"ShardIterator": body["ShardId"].(string)}
+		case "GetRecords":
+			shard := body["ShardIterator"].(string)
+			next := ""
+			if shard == "child" {
+				// The child shard stays open
+				next = "child"
+			}
+			page := records[shard]
+			records[shard] = nil
+			return 200, map[string]interface{}{"Records": page, "NextShardIterator": next}
+		}
+		t.Fatalf("unexpected action %s", action)
+		return 0, nil
+	})
+
+	c := &Consumer{
+		Service:      s,
+		StreamArn:    "arn",
+		IteratorType: Latest,
+	}
+	stop := errors.New("stop")
+	var order []string
+	err := c.Run(context.Background(), func(ctx context.Context, shard Shard, records []Record) error {
+		for _, r := range records {
+			var p paper
+			if err := r.UnmarshalNewImage(&p); err != nil {
+				return err
+			}
+			order = append(order, shard.ShardId+" "+r.DynamoDB.SequenceNumber)
+		}
+		if shard.ShardId == "child" {
+			return stop
+		}
+		return nil
+	})
+	if err != stop {
+		t.Fatalf("expected handler error, got %v", err)
+	}
+	if strings.Join(order, ",") != "parent 100,child 200,child 300" {
+		t.Errorf("unexpected order %v", order)
+	}
+	if strings.Join(requests, ",") != "parent LATEST,child TRIM_HORIZON" {
+		t.Errorf("unexpected iterators %v", requests)
+	}
+	if sequence, _ := c.Checkpoints.Get(context.Background(), "arn", "parent"); sequence != "100" {
+		t.Errorf("unexpected parent checkpoint %q", sequence)
+	}
+	if sequence, _ := c.Checkpoints.Get(context.Background(), "arn", "child"); sequence != "" {
+		t.Errorf("unexpected child checkpoint %q", sequence)
+	}
+}
+
+func TestConsumerResume(t *testing.T) {
+	var mu sync.Mutex
+	var iterators []string
+	s := testService(func(action string, body map[string]interface{}) (int, interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch action {
+		case "DescribeStream":
+			return 200, map[string]interface{}{
+				"StreamDescription": map[string]interface{}{
+					"Shards": []interface{}{map[string]string{"ShardId": "shard"}},
+				},
+			}
+		case "GetShardIterator":
+			iterators = append(iterators, body["ShardIteratorType"].(string)+" "+body["SequenceNumber"].(string))
+			return 200, map[string]string{"ShardIterator": "shard"}
+		case "GetRecords":
+			if len(iterators) == 1 {
+				return 400, map[string]string{"__type": "com.amazonaws#ExpiredIteratorException"}
+			}
+			return 200, map[string]interface{}{}
+		}
+		return 200, map[string]interface{}{}
+	})
+	checkpoints := &MemoryCheckpointer{}
+	checkpoints.Set(context.Background(), "arn", "shard", "42")
+	c := &Consumer{
+		Service:      s,
+		StreamArn:    "arn",
+		Checkpoints:  checkpoints,
+		PollInterval: time.Millisecond,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := c.Run(ctx, func(ctx context.Context, shard Shard, records []Record) error {
+		return nil
+	}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline, got %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(iterators) != 2 || iterators[0] != "AFTER_SEQUENCE_NUMBER 42" || iterators[1] != iterators[0] {
+		t.Errorf("unexpected iterators %v", iterators)
+	}
+}
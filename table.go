@@ -0,0 +1,105 @@
+package dynamodb
+
+import (
+	"context"
+	"iter"
+)
+
+// A Table gives typed access to the items of a table, T being the struct
+// type items are stored as.
+type Table[T any] struct {
+	Name    string
+	Service *Service
+}
+
+// Returns a Table of the given name, using the given service.
+func NewTable[T any](s *Service, name string) *Table[T] {
+	return &Table[T]{
+		Name:    name,
+		Service: s,
+	}
+}
+
+// Get the item corresponding to the keys of the given item, returning
+// ErrNotFound when it doesn't exist.
+func (t *Table[T]) Get(key T, opts ...Option) (T, error) {
+	var item T
+	found, err := t.Service.get(t.Name, &key, &item, opts)
+	if err == nil && !found {
+		return item, ErrNotFound
+	}
+	return item, err
+}
+
+// Create or replace the item.
+func (t *Table[T]) Put(item T) error {
+	return t.Service.Put(t.Name, &item)
+}
+
+// Deletes the item corresponding to the keys of the given item.
+func (t *Table[T]) Delete(key T) error {
+	return t.Service.Delete(t.Name, &key)
+}
+
+// Return all items sharing the hash key of the given item, and its range
+// key when set.
+func (t *Table[T]) Query(key T, opts ...Option) ([]T, error) {
+	items, err := t.Service.Query(t.Name, &key, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return values(items.([]*T)), nil
+}
+
+// Return all items in the table.
+func (t *Table[T]) Scan(opts ...Option) ([]T, error) {
+	item := new(T)
+	items, err := collect(t.Service.Scan(context.Background(), t.Name, item, opts...), item)
+	if err != nil {
+		return nil, err
+	}
+	return values(items.([]*T)), nil
+}
+
+// Iterate over all items in the table, fetching pages as needed.
+func (t *Table[T]) Iter(ctx context.Context, opts ...Option) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		it := t.Service.Scan(ctx, t.Name, new(T), opts...)
+		for it.Next() {
+			if !yield(*it.Item().(*T), nil) {
+				return
+			}
+		}
+		if err := it.Err(); err != nil {
+			var zero T
+			yield(zero, err)
+		}
+	}
+}
+
+// Return the items corresponding to the keys of the given items.
+func (t *Table[T]) BatchGet(keys []T, opts ...Option) ([]T, error) {
+	items, err := t.Service.BatchGet(t.Name, keys, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return values(items.([]*T)), nil
+}
+
+// Create or replace the given items.
+func (t *Table[T]) BatchPut(items []T) error {
+	return t.Service.BatchPut(t.Name, items)
+}
+
+// Deletes the items corresponding to the keys of the given items.
+func (t *Table[T]) BatchDelete(keys []T) error {
+	return t.Service.BatchDelete(t.Name, keys)
+}
+
+func values[T any](items []*T) []T {
+	s := make([]T, len(items))
+	for i, item := range items {
+		s[i] = *item
+	}
+	return s
+}
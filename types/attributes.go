@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -14,39 +15,107 @@ var (
 	ErrNilValue     = errors.New("dynamodb: value is nil")
 	ErrValuePointer = errors.New("dynamodb: value is not a pointer")
 	ErrValueStruct  = errors.New("dynamodb: value is not a struct")
+	ErrInlineType   = errors.New("dynamodb: inline field is not an AttributeValue")
 )
 
 var textMarshalerType = reflect.TypeOf(new(encoding.TextMarshaler)).Elem()
 var textUnmarshalerType = reflect.TypeOf(new(encoding.TextUnmarshaler)).Elem()
+var attributeValueType = reflect.TypeOf(AttributeValue(nil))
 
 type AttributeValue map[string]map[string]interface{}
 
-// Marshall struct into AttributeValue.
+// Marshall struct into AttributeValue. Attributes of zero value, such as
+// 0 or false, are omitted unless they are keys; use a pointer to store them.
 func Marshal(v interface{}, keys bool) (AttributeValue, error) {
 	s := reflect.Indirect(reflect.ValueOf(v))
 	if s.Kind() != reflect.Struct {
 		return nil, ErrValueStruct
 	}
+	return marshalStruct(s, keys)
+}
+
+func marshalStruct(s reflect.Value, keys bool) (AttributeValue, error) {
 	t := s.Type()
 	values := make(AttributeValue)
+	var inline AttributeValue
 	for i := 0; i < s.NumField(); i++ {
 		f := s.Field(i)
 		ft := t.Field(i)
 		tag := ft.Tag.Get("dynamo")
-		if ft.Anonymous || isEmptyValue(f) || tag == "-" {
-			// Skip anonymous fields and empty field
+		name, options := parseTag(tag)
+		key := options.Contains("hash") || options.Contains("range")
+		if ft.Anonymous || (isEmptyValue(f) && !key) || tag == "-" {
+			// Skip anonymous fields and empty field, keys being always set
 			continue
 		}
-		name, options := parseTag(tag)
 		if name == "" {
 			name = ft.Name
 		}
-		if keys && !(options.Contains("hash") || options.Contains("range")) {
+		if options.Contains("inline") {
+			if !f.Type().ConvertibleTo(attributeValueType) {
+				return nil, ErrInlineType
+			}
+			inline = f.Convert(attributeValueType).Interface().(AttributeValue)
+			continue
+		}
+		if keys && !key {
 			// Ignore non-keys field if asking only for keys
 			continue
 		}
 		marshaler := typeMarshaler(f.Type())
-		k, v := marshaler(f)
+		if options.Contains("ttl") && f.Type() == timeType {
+			marshaler = epochMarshaler
+		}
+		k, v, err := marshaler(f)
+		if err != nil {
+			return nil, err
+		}
+		values[name] = map[string]interface{}{
+			k: v,
+		}
+	}
+	if !keys {
+		// Write back attributes unknown to the struct
+		for name, value := range inline {
+			if _, present := values[name]; !present {
+				values[name] = value
+			}
+		}
+	}
+	return values, nil
+}
+
+// Marshall the attributes of the given key schema set in the given struct,
+// attributes of zero value being considered unset.
+func MarshalKeys(v interface{}, schema KeySchema) (AttributeValue, error) {
+	s := reflect.Indirect(reflect.ValueOf(v))
+	if s.Kind() != reflect.Struct {
+		return nil, ErrValueStruct
+	}
+	wanted := make(map[string]bool)
+	for _, key := range schema {
+		wanted[key.AttributeName] = true
+	}
+	t := s.Type()
+	values := make(AttributeValue)
+	for i := 0; i < s.NumField(); i++ {
+		f := s.Field(i)
+		ft := t.Field(i)
+		tag := ft.Tag.Get("dynamo")
+		if ft.Anonymous || tag == "-" || f.IsZero() {
+			continue
+		}
+		name, _ := parseTag(tag)
+		if name == "" {
+			name = ft.Name
+		}
+		if !wanted[name] {
+			continue
+		}
+		k, v, err := typeMarshaler(f.Type())(f)
+		if err != nil {
+			return nil, err
+		}
 		values[name] = map[string]interface{}{
 			k: v,
 		}
@@ -54,7 +123,7 @@ func Marshal(v interface{}, keys bool) (AttributeValue, error) {
 	return values, nil
 }
 
-type marshalFunc func(v reflect.Value) (string, interface{})
+type marshalFunc func(v reflect.Value) (string, interface{}, error)
 
 func typeMarshaler(t reflect.Type) marshalFunc {
 	if t.Implements(textMarshalerType) {
@@ -77,37 +146,47 @@ func typeMarshaler(t reflect.Type) marshalFunc {
 		return newSliceMarshaler(t)
 	case reflect.Array:
 		return newArrayMarshaler(t)
+	case reflect.Struct:
+		return structMarshaler
+	case reflect.Map:
+		return newMapMarshaler(t)
+	case reflect.Ptr:
+		return newPtrMarshaler(t)
+	case reflect.Interface:
+		return interfaceMarshaler
 	default:
-		panic(fmt.Sprintf("dynamodb: %s type is not supported", t.Kind()))
+		return func(v reflect.Value) (string, interface{}, error) {
+			return "", nil, fmt.Errorf("dynamodb: %s type is not supported", t)
+		}
 	}
 }
 
-func boolMarshaler(v reflect.Value) (string, interface{}) {
-	return "S", strconv.FormatBool(v.Bool())
+func boolMarshaler(v reflect.Value) (string, interface{}, error) {
+	return "S", strconv.FormatBool(v.Bool()), nil
 }
 
-func intMarshaler(v reflect.Value) (string, interface{}) {
-	return "N", strconv.FormatInt(v.Int(), 10)
+func intMarshaler(v reflect.Value) (string, interface{}, error) {
+	return "N", strconv.FormatInt(v.Int(), 10), nil
 }
 
-func uintMarshaler(v reflect.Value) (string, interface{}) {
-	return "N", strconv.FormatUint(v.Uint(), 10)
+func uintMarshaler(v reflect.Value) (string, interface{}, error) {
+	return "N", strconv.FormatUint(v.Uint(), 10), nil
 }
 
-func float32Marshaler(v reflect.Value) (string, interface{}) {
-	return "N", strconv.FormatFloat(v.Float(), 'f', -1, 32)
+func float32Marshaler(v reflect.Value) (string, interface{}, error) {
+	return "N", strconv.FormatFloat(v.Float(), 'f', -1, 32), nil
 }
 
-func float64Marshaler(v reflect.Value) (string, interface{}) {
-	return "N", strconv.FormatFloat(v.Float(), 'f', -1, 64)
+func float64Marshaler(v reflect.Value) (string, interface{}, error) {
+	return "N", strconv.FormatFloat(v.Float(), 'f', -1, 64), nil
 }
 
-func stringMarshaler(v reflect.Value) (string, interface{}) {
-	return "S", v.String()
+func stringMarshaler(v reflect.Value) (string, interface{}, error) {
+	return "S", v.String(), nil
 }
 
-func byteMarshaler(v reflect.Value) (string, interface{}) {
-	return "B", string(v.Bytes())
+func byteMarshaler(v reflect.Value) (string, interface{}, error) {
+	return "B", string(v.Bytes()), nil
 }
 
 func newSliceMarshaler(t reflect.Type) marshalFunc {
@@ -117,29 +196,157 @@ func newSliceMarshaler(t reflect.Type) marshalFunc {
 	return newArrayMarshaler(t)
 }
 
+// Arrays of strings, numbers and binaries are marshaled as sets, other
+// arrays as lists.
 func newArrayMarshaler(t reflect.Type) marshalFunc {
 	marshaler := typeMarshaler(t.Elem())
-	return func(v reflect.Value) (string, interface{}) {
-		var array []string
-		var kind string
-		n := v.Len()
-		for i := 0; i < n; i++ {
-			k, e := marshaler(v.Index(i))
-			array = append(array, e.(string))
-			kind = k
+	kind := attributeType(t)
+	if kind != "L" {
+		return func(v reflect.Value) (string, interface{}, error) {
+			var array []string
+			n := v.Len()
+			for i := 0; i < n; i++ {
+				k, e, err := marshaler(v.Index(i))
+				if err != nil {
+					return "", nil, err
+				}
+				if k+"S" != kind {
+					return "", nil, fmt.Errorf("dynamodb: %s attribute in a set of type %s", k, kind)
+				}
+				array = append(array, e.(string))
+			}
+			return kind, array, nil
+		}
+	}
+	return func(v reflect.Value) (string, interface{}, error) {
+		list := make([]map[string]interface{}, v.Len())
+		for i := range list {
+			k, e, err := marshaler(v.Index(i))
+			if err != nil {
+				return "", nil, err
+			}
+			list[i] = map[string]interface{}{k: e}
 		}
-		return fmt.Sprintf("%sS", kind), array
+		return "L", list, nil
 	}
 }
 
-func textMarshaler(v reflect.Value) (string, interface{}) {
+func structMarshaler(v reflect.Value) (string, interface{}, error) {
+	a, err := marshalStruct(v, false)
+	return "M", a, err
+}
+
+func newMapMarshaler(t reflect.Type) marshalFunc {
+	if t.Key().Kind() != reflect.String {
+		return func(v reflect.Value) (string, interface{}, error) {
+			return "", nil, fmt.Errorf("dynamodb: %s type is not supported", t)
+		}
+	}
+	marshaler := typeMarshaler(t.Elem())
+	return func(v reflect.Value) (string, interface{}, error) {
+		a := make(AttributeValue, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			k, e, err := marshaler(iter.Value())
+			if err != nil {
+				return "", nil, err
+			}
+			a[iter.Key().String()] = map[string]interface{}{k: e}
+		}
+		return "M", a, nil
+	}
+}
+
+func newPtrMarshaler(t reflect.Type) marshalFunc {
+	marshaler := typeMarshaler(t.Elem())
+	return func(v reflect.Value) (string, interface{}, error) {
+		if v.IsNil() {
+			return "NULL", true, nil
+		}
+		return marshaler(v.Elem())
+	}
+}
+
+// Values of interfaces are marshaled as decoded into documents, booleans
+// being BOOL attributes.
+func interfaceMarshaler(v reflect.Value) (string, interface{}, error) {
+	if v.IsNil() {
+		return "NULL", true, nil
+	}
+	e := v.Elem()
+	if e.Kind() == reflect.Bool {
+		return "BOOL", e.Bool(), nil
+	}
+	return typeMarshaler(e.Type())(e)
+}
+
+// Time to live attributes are stored as seconds since the Unix epoch.
+func epochMarshaler(v reflect.Value) (string, interface{}, error) {
+	t := v.Interface().(time.Time)
+	return "N", strconv.FormatInt(t.Unix(), 10), nil
+}
+
+func textMarshaler(v reflect.Value) (string, interface{}, error) {
 	m := v.Interface().(encoding.TextMarshaler)
-	b, _ := m.MarshalText()
-	return "S", string(b)
+	b, err := m.MarshalText()
+	return "S", string(b), err
 }
 
 // Unmarshall AttributeValue into struct.
 func Unmarshal(a AttributeValue, v interface{}) error {
+	return new(Decoder).Unmarshal(a, v)
+}
+
+// Unmarshall AttributeValue into struct, reporting unknown and mistyped
+// attributes as a StrictError.
+func UnmarshalStrict(a AttributeValue, v interface{}) error {
+	d := &Decoder{Strict: true}
+	return d.Unmarshal(a, v)
+}
+
+func MakeSlice(a []AttributeValue, v interface{}) (interface{}, error) {
+	return new(Decoder).MakeSlice(a, v)
+}
+
+// A Decoder unmarshals AttributeValue into structs.
+type Decoder struct {
+	// Strict reports attributes that don't map to any field and attributes
+	// whose type doesn't match their field instead of ignoring them.
+	Strict bool
+}
+
+// Unmarshall AttributeValue into struct.
+func (d *Decoder) Unmarshal(a AttributeValue, v interface{}) error {
+	var errs StrictError
+	if err := d.unmarshal(a, v, "", &errs); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Unmarshall every AttributeValue into a new element of a slice of
+// v's type.
+func (d *Decoder) MakeSlice(a []AttributeValue, v interface{}) (interface{}, error) {
+	var errs StrictError
+	t := reflect.ValueOf(v).Type()
+	slice := reflect.MakeSlice(reflect.SliceOf(t), 0, 0)
+	for i, av := range a {
+		e := reflect.New(t.Elem())
+		if err := d.unmarshal(av, e.Interface(), fmt.Sprintf("[%d].", i), &errs); err != nil {
+			return nil, err
+		}
+		slice = reflect.Append(slice, e)
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return slice.Interface(), nil
+}
+
+func (d *Decoder) unmarshal(a AttributeValue, v interface{}, path string, errs *StrictError) error {
 	rv := reflect.ValueOf(v)
 	if rv.Kind() != reflect.Ptr {
 		return ErrValuePointer
@@ -152,6 +359,8 @@ func Unmarshal(a AttributeValue, v interface{}) error {
 		return ErrValueStruct
 	}
 	t := s.Type()
+	known := make(map[string]bool)
+	var inline reflect.Value
 	for i := 0; i < s.NumField(); i++ {
 		f := s.Field(i)
 		ft := t.Field(i)
@@ -160,18 +369,39 @@ func Unmarshal(a AttributeValue, v interface{}) error {
 			// Ignore unusable fields
 			continue
 		}
-		name, _ := parseTag(tag)
+		name, options := parseTag(tag)
 		if name == "" {
 			name = ft.Name
 		}
+		if options.Contains("inline") {
+			if !attributeValueType.ConvertibleTo(f.Type()) {
+				return ErrInlineType
+			}
+			inline = f
+			continue
+		}
+		known[name] = true
 		values, present := a[name]
 		if !present {
 			// Field not present in attributes values
 			continue
 		}
-		unmarshaler := typeUnmarshaler(f.Type())
-		for _, v := range values {
-			fv, err := unmarshaler(reflect.ValueOf(v))
+		want := attributeType(f.Type())
+		var unmarshaler unmarshalFunc
+		if options.Contains("ttl") && f.Type() == timeType {
+			want, unmarshaler = "N", epochUnmarshaler
+		}
+		for k, v := range values {
+			if d.Strict && !accepts(k, want, f.Type()) {
+				*errs = append(*errs, &AttributeError{
+					Path:  path + name,
+					Type:  k,
+					Field: ft.Name,
+					Want:  want,
+				})
+				continue
+			}
+			fv, err := d.decode(k, v, f.Type(), unmarshaler, path+name, errs)
 			if err != nil {
 				return err
 			}
@@ -179,20 +409,331 @@ func Unmarshal(a AttributeValue, v interface{}) error {
 			f.Set(fc)
 		}
 	}
+	var unknown []string
+	for name := range a {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if inline.IsValid() {
+		// Collect attributes unknown to the struct, replacing those of a
+		// previous decoding
+		inline.Set(reflect.Zero(inline.Type()))
+		if len(unknown) > 0 {
+			inline.Set(reflect.MakeMapWithSize(inline.Type(), len(unknown)))
+		}
+		for _, name := range unknown {
+			inline.SetMapIndex(reflect.ValueOf(name).Convert(inline.Type().Key()), reflect.ValueOf(a[name]).Convert(inline.Type().Elem()))
+		}
+		return nil
+	}
+	if d.Strict {
+		sort.Strings(unknown)
+		for _, name := range unknown {
+			*errs = append(*errs, &AttributeError{
+				Path: path + name,
+				Type: attributeKind(a[name]),
+			})
+		}
+	}
 	return nil
 }
 
-func MakeSlice(a []AttributeValue, v interface{}) (interface{}, error) {
-	t := reflect.ValueOf(v).Type()
-	slice := reflect.MakeSlice(reflect.SliceOf(t), 0, 0)
-	for _, av := range a {
-		e := reflect.New(t.Elem())
-		if err := Unmarshal(av, e.Interface()); err != nil {
-			return nil, err
+// decode decodes a single attribute value of the given type into a value
+// of type t, documents being decoded recursively. The unmarshaler, when
+// not nil, overrides the one of type t.
+func (d *Decoder) decode(kind string, v interface{}, t reflect.Type, unmarshaler unmarshalFunc, path string, errs *StrictError) (reflect.Value, error) {
+	want := attributeType(t)
+	switch {
+	case kind == "NULL":
+		return reflect.Zero(t), nil
+	case unmarshaler != nil:
+		e, err := unmarshaler(reflect.ValueOf(v))
+		if err != nil {
+			return e, fmt.Errorf("dynamodb: attribute %s of type %s can't be decoded into %s: %w", path, kind, t, err)
 		}
-		slice = reflect.Append(slice, e)
+		return e, nil
+	case t.Kind() == reflect.Ptr:
+		e, err := d.decode(kind, v, t.Elem(), nil, path, errs)
+		if err != nil {
+			return e, err
+		}
+		p := reflect.New(t.Elem())
+		p.Elem().Set(e.Convert(t.Elem()))
+		return p, nil
+	case t.Kind() == reflect.Interface && t.NumMethod() == 0:
+		if doc := document(kind, v); doc != nil {
+			return reflect.ValueOf(doc), nil
+		}
+		return reflect.Zero(t), nil
+	case kind == "M" && t.Kind() == reflect.Struct && want == "M":
+		a, ok := members(v)
+		if !ok {
+			break
+		}
+		s := reflect.New(t)
+		if err := d.unmarshal(a, s.Interface(), path+".", errs); err != nil {
+			return s, err
+		}
+		return s.Elem(), nil
+	case kind == "M" && t.Kind() == reflect.Map && t.Key().Kind() == reflect.String:
+		a, ok := members(v)
+		if !ok {
+			break
+		}
+		m := reflect.MakeMapWithSize(t, len(a))
+		for name, values := range a {
+			for k, v := range values {
+				if d.rejects(k, t.Elem(), path+"."+name, errs) {
+					continue
+				}
+				e, err := d.decode(k, v, t.Elem(), nil, path+"."+name, errs)
+				if err != nil {
+					return m, err
+				}
+				m.SetMapIndex(reflect.ValueOf(name).Convert(t.Key()), e.Convert(t.Elem()))
+			}
+		}
+		return m, nil
+	case kind == "L" && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array):
+		l, ok := elements(v)
+		if !ok {
+			break
+		}
+		s := reflect.MakeSlice(reflect.SliceOf(t.Elem()), len(l), len(l))
+		for i, values := range l {
+			for k, v := range values {
+				if d.rejects(k, t.Elem(), fmt.Sprintf("%s[%d]", path, i), errs) {
+					continue
+				}
+				e, err := d.decode(k, v, t.Elem(), nil, fmt.Sprintf("%s[%d]", path, i), errs)
+				if err != nil {
+					return s, err
+				}
+				s.Index(i).Set(e.Convert(t.Elem()))
+			}
+		}
+		if t.Kind() == reflect.Array {
+			a := reflect.New(t).Elem()
+			reflect.Copy(a, s)
+			return a, nil
+		}
+		return s, nil
+	case kind == "BOOL":
+		b, ok := v.(bool)
+		if !ok {
+			break
+		}
+		if t.Kind() == reflect.String {
+			return reflect.ValueOf(strconv.FormatBool(b)), nil
+		}
+		if t.Kind() == reflect.Bool {
+			return reflect.ValueOf(b), nil
+		}
+	case kind != "M" && kind != "L" && want != "" && want != "M" && want != "L":
+		e, err := typeUnmarshaler(t)(reflect.ValueOf(v))
+		if err != nil {
+			return e, fmt.Errorf("dynamodb: attribute %s of type %s can't be decoded into %s: %w", path, kind, t, err)
+		}
+		return e, nil
 	}
-	return slice.Interface(), nil
+	return reflect.Value{}, fmt.Errorf("dynamodb: attribute %s of type %s can't be decoded into %s", path, kind, t)
+}
+
+// rejects reports, in strict mode, whether an element of a map or list of
+// the given type can't be decoded into t, adding an error to errs.
+func (d *Decoder) rejects(kind string, t reflect.Type, path string, errs *StrictError) bool {
+	want := attributeType(t)
+	if !d.Strict || accepts(kind, want, t) {
+		return false
+	}
+	*errs = append(*errs, &AttributeError{
+		Path:  path,
+		Type:  kind,
+		Field: t.String(),
+		Want:  want,
+	})
+	return true
+}
+
+// document returns the Go value of a single attribute value, maps and
+// lists being decoded into map[string]interface{} and []interface{}.
+func document(kind string, v interface{}) interface{} {
+	switch kind {
+	case "NULL":
+		return nil
+	case "N":
+		s, _ := v.(string)
+		f, _ := strconv.ParseFloat(s, 64)
+		return f
+	case "NS":
+		var numbers []float64
+		if l, ok := v.([]interface{}); ok {
+			for _, e := range l {
+				s, _ := e.(string)
+				f, _ := strconv.ParseFloat(s, 64)
+				numbers = append(numbers, f)
+			}
+		}
+		return numbers
+	case "SS":
+		var values []string
+		if l, ok := v.([]interface{}); ok {
+			for _, e := range l {
+				s, _ := e.(string)
+				values = append(values, s)
+			}
+		}
+		return values
+	case "M":
+		a, _ := members(v)
+		m := make(map[string]interface{}, len(a))
+		for name, values := range a {
+			for k, v := range values {
+				m[name] = document(k, v)
+			}
+		}
+		return m
+	case "L":
+		l, _ := elements(v)
+		list := make([]interface{}, len(l))
+		for i, values := range l {
+			for k, v := range values {
+				list[i] = document(k, v)
+			}
+		}
+		return list
+	}
+	return v
+}
+
+// members returns the attributes of a map attribute value.
+func members(v interface{}) (AttributeValue, bool) {
+	switch m := v.(type) {
+	case AttributeValue:
+		return m, true
+	case map[string]map[string]interface{}:
+		return m, true
+	case map[string]interface{}:
+		a := make(AttributeValue, len(m))
+		for name, e := range m {
+			values, ok := e.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			a[name] = values
+		}
+		return a, true
+	}
+	return nil, false
+}
+
+// elements returns the attributes of a list attribute value.
+func elements(v interface{}) ([]map[string]interface{}, bool) {
+	switch l := v.(type) {
+	case []map[string]interface{}:
+		return l, true
+	case []interface{}:
+		values := make([]map[string]interface{}, len(l))
+		for i, e := range l {
+			m, ok := e.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			values[i] = m
+		}
+		return values, true
+	}
+	return nil, false
+}
+
+// accepts reports whether an attribute of the given type can be decoded
+// into a field of type t, expecting attributes of type want.
+func accepts(kind, want string, t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case kind == want, kind == "NULL", want == "":
+		return true
+	case kind == "BOOL":
+		// Booleans are decoded into strings as "true" or "false"
+		return t.Kind() == reflect.Bool || t.Kind() == reflect.String
+	case kind == "L":
+		return t.Kind() == reflect.Slice || t.Kind() == reflect.Array
+	}
+	return false
+}
+
+// An AttributeError describes an attribute rejected by strict decoding.
+type AttributeError struct {
+	Path  string // Attribute path, e.g. "[2].authors"
+	Type  string // DynamoDB type of the attribute, e.g. "SS"
+	Field string // Struct field the attribute maps to, or element type, empty if unknown
+	Want  string // DynamoDB type expected by the field
+}
+
+func (e *AttributeError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("dynamodb: unknown attribute %s", e.Path)
+	}
+	return fmt.Sprintf("dynamodb: attribute %s of type %s can't be decoded into field %s of type %s",
+		e.Path, e.Type, e.Field, e.Want)
+}
+
+// A StrictError lists every attribute rejected by strict decoding.
+type StrictError []*AttributeError
+
+func (e StrictError) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// attributeType returns the DynamoDB type values of type t are marshaled as,
+// or empty for interfaces which accept any type.
+func attributeType(t reflect.Type) string {
+	if t.Implements(textMarshalerType) || reflect.PtrTo(t).Implements(textMarshalerType) {
+		return "S"
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return attributeType(t.Elem())
+	case reflect.Struct, reflect.Map:
+		return "M"
+	case reflect.Bool, reflect.String:
+		return "S"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "N"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return "N"
+	case reflect.Float32, reflect.Float64:
+		return "N"
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "B"
+		}
+		fallthrough
+	case reflect.Array:
+		switch k := attributeType(t.Elem()); k {
+		case "S", "N", "B":
+			return k + "S"
+		}
+		return "L"
+	}
+	return ""
+}
+
+// attributeKind returns the type of a single attribute value.
+func attributeKind(values map[string]interface{}) string {
+	kinds := make([]string, 0, len(values))
+	for k := range values {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+	return strings.Join(kinds, ",")
 }
 
 type unmarshalFunc func(v reflect.Value) (reflect.Value, error)
@@ -246,6 +787,11 @@ func stringUnmarshaler(v reflect.Value) (reflect.Value, error) {
 	return reflect.ValueOf(s), nil
 }
 
+func epochUnmarshaler(v reflect.Value) (reflect.Value, error) {
+	i, err := strconv.ParseInt(v.String(), 10, 64)
+	return reflect.ValueOf(time.Unix(i, 0).UTC()), err
+}
+
 func byteUnmarshaler(v reflect.Value) (reflect.Value, error) {
 	b := []byte(v.String())
 	return reflect.ValueOf(b), nil
@@ -266,7 +812,12 @@ func newArrayUnmarshaler(t reflect.Type) unmarshalFunc {
 		n := v.Len()
 		s := reflect.MakeSlice(st, 0, 0)
 		for i := 0; i < n; i++ {
-			value, err := unmarshaler(v.Index(i))
+			e := v.Index(i)
+			if e.Kind() == reflect.Interface {
+				// Elements of lists decoded from JSON
+				e = e.Elem()
+			}
+			value, err := unmarshaler(e)
 			if err != nil {
 				return s, err
 			}
@@ -287,6 +838,8 @@ func newTextUnmarshaler(t reflect.Type) unmarshalFunc {
 
 var timeType = reflect.TypeOf(time.Time{})
 
+// Report whether the value is omitted when marshaled, zero numbers and
+// false included.
 func isEmptyValue(v reflect.Value) bool {
 	if v.Type() == timeType {
 		t := v.Interface().(time.Time)
@@ -295,6 +848,14 @@ func isEmptyValue(v reflect.Value) bool {
 	switch v.Kind() {
 	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
 		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
 	case reflect.Interface, reflect.Ptr:
 		return v.IsNil()
 	}
@@ -328,3 +889,13 @@ func (o options) Contains(name string) bool {
 	}
 	return false
 }
+
+// Returns the values of the options of the form key=value.
+func (o options) Values(key string) (values []string) {
+	for _, option := range strings.Split(string(o), ",") {
+		if k, v, found := strings.Cut(option, "="); found && k == key {
+			values = append(values, v)
+		}
+	}
+	return values
+}
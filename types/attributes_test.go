@@ -1,7 +1,9 @@
 package types
 
 import (
+	"encoding/json"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -104,6 +106,51 @@ func TestMarshall(t *testing.T) {
 	}
 }
 
+type keyTest struct {
+	Year  int  `dynamo:"year,hash"`
+	Count int  `dynamo:"count"`
+	Valid bool `dynamo:"valid"`
+}
+
+func TestMarshallZeroKeys(t *testing.T) {
+	control := AttributeValue{
+		"year": {
+			"N": "0",
+		},
+	}
+	for _, keys := range []bool{true, false} {
+		v, err := Marshal(keyTest{}, keys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(v, control) {
+			t.Errorf("got %v wants %v", v, control)
+		}
+	}
+}
+
+func TestMarshallZeroPointers(t *testing.T) {
+	count, valid := 0, false
+	v, err := Marshal(struct {
+		Count *int  `dynamo:"count"`
+		Valid *bool `dynamo:"valid"`
+	}{&count, &valid}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	control := AttributeValue{
+		"count": {
+			"N": "0",
+		},
+		"valid": {
+			"S": "false",
+		},
+	}
+	if !reflect.DeepEqual(v, control) {
+		t.Errorf("got %v wants %v", v, control)
+	}
+}
+
 func TestUnmarshall(t *testing.T) {
 	for _, e := range marshallTests {
 		var item structTest
@@ -116,3 +163,302 @@ func TestUnmarshall(t *testing.T) {
 		}
 	}
 }
+
+func TestUnmarshallStrict(t *testing.T) {
+	var item structTest
+	err := UnmarshalStrict(AttributeValue{
+		"Int": {
+			"SS": []interface{}{"a", "b"},
+		},
+		"String": {
+			"S": "abc",
+		},
+		"Unknown": {
+			"N": "12",
+		},
+	}, &item)
+	control := StrictError{
+		{Path: "Int", Type: "SS", Field: "Int", Want: "N"},
+		{Path: "Unknown", Type: "N"},
+	}
+	if !reflect.DeepEqual(err, control) {
+		t.Errorf("got %v wants %v", err, control)
+	}
+	if item.String != "abc" {
+		t.Errorf("got %v wants %v", item.String, "abc")
+	}
+}
+
+func TestUnmarshallStrictValid(t *testing.T) {
+	for _, e := range marshallTests {
+		var item structTest
+		if err := UnmarshalStrict(e.value, &item); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestUnmarshallStrictElements(t *testing.T) {
+	var item struct {
+		Scores map[string]int `dynamo:"scores"`
+		Tags   []string       `dynamo:"tags"`
+	}
+	err := UnmarshalStrict(AttributeValue{
+		"scores": {
+			"M": map[string]interface{}{
+				"a": map[string]interface{}{"SS": []interface{}{"x"}},
+				"b": map[string]interface{}{"N": "2"},
+			},
+		},
+		"tags": {
+			"L": []interface{}{
+				map[string]interface{}{"N": "1"},
+				map[string]interface{}{"S": "x"},
+			},
+		},
+	}, &item)
+	control := StrictError{
+		{Path: "scores.a", Type: "SS", Field: "int", Want: "N"},
+		{Path: "tags[0]", Type: "N", Field: "string", Want: "S"},
+	}
+	if !reflect.DeepEqual(err, control) {
+		t.Errorf("got %v wants %v", err, control)
+	}
+	if item.Scores["b"] != 2 || len(item.Tags) != 2 || item.Tags[1] != "x" {
+		t.Errorf("got %v", item)
+	}
+}
+
+func TestUnmarshallInvalidNumber(t *testing.T) {
+	var item structTest
+	err := Unmarshal(AttributeValue{"Int": {"N": "abc"}}, &item)
+	if err == nil || !strings.Contains(err.Error(), "attribute Int of type N") {
+		t.Errorf("got %v wants an error for attribute Int", err)
+	}
+}
+
+func TestUnmarshallStrictBoolString(t *testing.T) {
+	var item structTest
+	if err := UnmarshalStrict(AttributeValue{"String": {"BOOL": true}}, &item); err != nil {
+		t.Fatal(err)
+	}
+	if item.String != "true" {
+		t.Errorf("got %v wants %v", item.String, "true")
+	}
+}
+
+func TestMakeSliceStrict(t *testing.T) {
+	d := &Decoder{Strict: true}
+	_, err := d.MakeSlice([]AttributeValue{
+		{"Int": {"N": "8"}},
+		{"Other": {"S": "abc"}},
+	}, &structTest{})
+	control := StrictError{
+		{Path: "[1].Other", Type: "S"},
+	}
+	if !reflect.DeepEqual(err, control) {
+		t.Errorf("got %v wants %v", err, control)
+	}
+}
+
+type inlineTest struct {
+	String string         `dynamo:"string"`
+	Others AttributeValue `dynamo:",inline"`
+}
+
+func TestInline(t *testing.T) {
+	value := AttributeValue{
+		"string": {
+			"S": "abc",
+		},
+		"number": {
+			"N": "12",
+		},
+	}
+	var item inlineTest
+	if err := UnmarshalStrict(value, &item); err != nil {
+		t.Fatal(err)
+	}
+	others := AttributeValue{
+		"number": {
+			"N": "12",
+		},
+	}
+	if !reflect.DeepEqual(item.Others, others) {
+		t.Errorf("got %v wants %v", item.Others, others)
+	}
+	v, err := Marshal(item, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(v, value) {
+		t.Errorf("got %v wants %v", v, value)
+	}
+	previous := item.Others
+	previous["stale"] = map[string]interface{}{"S": "def"}
+	if err := UnmarshalStrict(value, &item); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(item.Others, others) {
+		t.Errorf("got %v wants %v", item.Others, others)
+	}
+	if len(previous) != 2 {
+		t.Errorf("got %v wants %v", len(previous), 2)
+	}
+	if err := UnmarshalStrict(AttributeValue{"string": {"S": "abc"}}, &item); err != nil {
+		t.Fatal(err)
+	}
+	if item.Others != nil {
+		t.Errorf("got %v wants %v", item.Others, nil)
+	}
+}
+
+type ttlTest struct {
+	Expires time.Time `dynamo:"expires,ttl"`
+}
+
+func TestTimeToLive(t *testing.T) {
+	item := ttlTest{
+		Expires: time.Date(2013, 12, 12, 17, 55, 30, 0, time.UTC),
+	}
+	value := AttributeValue{
+		"expires": {
+			"N": "1386870930",
+		},
+	}
+	v, err := Marshal(item, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(v, value) {
+		t.Errorf("got %v wants %v", v, value)
+	}
+	var control ttlTest
+	if err := UnmarshalStrict(value, &control); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(control, item) {
+		t.Errorf("got %v wants %v", control, item)
+	}
+}
+
+type address struct {
+	City string `dynamo:"city"`
+	Zip  string `dynamo:"zip"`
+}
+
+type documentTest struct {
+	Title    string                 `dynamo:"title"`
+	Tags     []string               `dynamo:"tags"`
+	Address  address                `dynamo:"address"`
+	Previous *address               `dynamo:"previous"`
+	History  []address              `dynamo:"history"`
+	Scores   map[string]int         `dynamo:"scores"`
+	Mixed    []interface{}          `dynamo:"mixed"`
+	Extra    map[string]interface{} `dynamo:"extra"`
+	Active   bool                   `dynamo:"active"`
+	Deleted  *bool                  `dynamo:"deleted"`
+	Note     string                 `dynamo:"note"`
+}
+
+const documentJSON = `{
+	"title": {"S": "a"},
+	"tags": {"SS": ["x", "y"]},
+	"address": {"M": {"city": {"S": "Paris"}, "zip": {"S": "75001"}}},
+	"previous": {"M": {"city": {"S": "Lyon"}}},
+	"history": {"L": [{"M": {"city": {"S": "Nice"}}}, {"NULL": true}]},
+	"scores": {"M": {"a": {"N": "1"}, "b": {"N": "2"}}},
+	"mixed": {"L": [{"S": "s"}, {"N": "1.5"}, {"BOOL": true}, {"NULL": true}, {"L": [{"S": "t"}]}]},
+	"extra": {"M": {"nested": {"M": {"n": {"N": "3"}}}, "set": {"NS": ["1", "2"]}}},
+	"active": {"BOOL": true},
+	"deleted": {"BOOL": false},
+	"note": {"NULL": true}
+}`
+
+func TestUnmarshallDocument(t *testing.T) {
+	var a AttributeValue
+	if err := json.Unmarshal([]byte(documentJSON), &a); err != nil {
+		t.Fatal(err)
+	}
+	v := documentTest{Note: "stale"}
+	if err := UnmarshalStrict(a, &v); err != nil {
+		t.Fatal(err)
+	}
+	deleted := false
+	expected := documentTest{
+		Title:    "a",
+		Tags:     []string{"x", "y"},
+		Address:  address{City: "Paris", Zip: "75001"},
+		Previous: &address{City: "Lyon"},
+		History:  []address{{City: "Nice"}, {}},
+		Scores:   map[string]int{"a": 1, "b": 2},
+		Mixed:    []interface{}{"s", 1.5, true, nil, []interface{}{"t"}},
+		Extra: map[string]interface{}{
+			"nested": map[string]interface{}{"n": 3.0},
+			"set":    []float64{1, 2},
+		},
+		Active:  true,
+		Deleted: &deleted,
+	}
+	if !reflect.DeepEqual(v, expected) {
+		t.Errorf("got %v wants %v", v, expected)
+	}
+}
+
+func TestMarshallDocument(t *testing.T) {
+	var a AttributeValue
+	if err := json.Unmarshal([]byte(documentJSON), &a); err != nil {
+		t.Fatal(err)
+	}
+	var expected documentTest
+	if err := Unmarshal(a, &expected); err != nil {
+		t.Fatal(err)
+	}
+	value, err := Marshal(expected, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded AttributeValue
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	var v documentTest
+	if err := UnmarshalStrict(decoded, &v); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(v, expected) {
+		t.Errorf("got %v wants %v", v, expected)
+	}
+	if _, err := Marshal(struct{ C chan int }{make(chan int)}, false); err == nil {
+		t.Errorf("got %v wants an error", err)
+	}
+}
+
+func TestUnmarshallDocumentStrict(t *testing.T) {
+	a := AttributeValue{
+		"address": {"M": map[string]interface{}{
+			"city":    map[string]interface{}{"N": "1"},
+			"country": map[string]interface{}{"S": "FR"},
+		}},
+	}
+	var v documentTest
+	err := UnmarshalStrict(a, &v)
+	errs, ok := err.(StrictError)
+	if !ok || len(errs) != 2 {
+		t.Fatalf("got %v wants 2 strict errors", err)
+	}
+	paths := []string{errs[0].Path, errs[1].Path}
+	control := []string{"address.city", "address.country"}
+	if !reflect.DeepEqual(paths, control) {
+		t.Errorf("got %v wants %v", paths, control)
+	}
+
+	a = AttributeValue{"address": {"L": []interface{}{}}}
+	if err := Unmarshal(a, &v); err == nil {
+		t.Errorf("got %v wants an error decoding a list into a struct", err)
+	}
+}
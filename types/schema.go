@@ -1,8 +1,11 @@
 package types
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
+	"sort"
+	"strings"
 )
 
 type AttributeDefinitions []AttributeDefinition
@@ -19,20 +22,84 @@ type KeySchemaElement struct {
 	KeyType       string
 }
 
+type Projection struct {
+	ProjectionType   string
+	NonKeyAttributes []string `json:",omitempty"`
+}
+
+type GlobalSecondaryIndex struct {
+	IndexName             string
+	KeySchema             KeySchema
+	Projection            Projection
+	ProvisionedThroughput *ProvisionedThroughput `json:",omitempty"`
+}
+
+type LocalSecondaryIndex struct {
+	IndexName  string
+	KeySchema  KeySchema
+	Projection Projection
+}
+
 type ProvisionedThroughput struct {
 	ReadCapacityUnits  int
 	WriteCapacityUnits int
 }
 
-type Table struct {
-	AttributeDefinitions  AttributeDefinitions
-	CreationDateTime      float64
-	ItemCount             int
-	KeySchema             KeySchema
+type ConsumedCapacity struct {
+	TableName              string
+	CapacityUnits          float64
+	ReadCapacityUnits      float64
+	WriteCapacityUnits     float64
+	Table                  *Capacity
+	GlobalSecondaryIndexes map[string]*Capacity
+	LocalSecondaryIndexes  map[string]*Capacity
+}
+
+type Capacity struct {
+	CapacityUnits      float64
+	ReadCapacityUnits  float64
+	WriteCapacityUnits float64
+}
+
+type SSESpecification struct {
+	Enabled        bool
+	SSEType        string `json:",omitempty"`
+	KMSMasterKeyId string `json:",omitempty"`
+}
+
+type StreamSpecification struct {
+	StreamEnabled  bool
+	StreamViewType string `json:",omitempty"`
+}
+
+type TimeToLiveSpecification struct {
+	AttributeName string
+	Enabled       bool
+}
+
+type TimeToLiveDescription struct {
+	AttributeName    string
+	TimeToLiveStatus string
+}
+
+type Tag struct {
+	Key   string
+	Value string
+}
+
+type GlobalSecondaryIndexUpdate struct {
+	Create *GlobalSecondaryIndex             `json:",omitempty"`
+	Update *UpdateGlobalSecondaryIndexAction `json:",omitempty"`
+	Delete *DeleteGlobalSecondaryIndexAction `json:",omitempty"`
+}
+
+type UpdateGlobalSecondaryIndexAction struct {
+	IndexName             string
 	ProvisionedThroughput ProvisionedThroughput
-	TableName             string
-	TableSizeBytes        int
-	TableStatus           string
+}
+
+type DeleteGlobalSecondaryIndexAction struct {
+	IndexName string
 }
 
 type WriteRequests map[string][]*WriteRequest
@@ -42,6 +109,15 @@ type WriteRequest struct {
 	DeleteRequest *DeleteRequest
 }
 
+type ReadRequests map[string]*KeysAndAttributes
+
+type KeysAndAttributes struct {
+	Keys                     []AttributeValue
+	ConsistentRead           bool              `json:",omitempty"`
+	ProjectionExpression     string            `json:",omitempty"`
+	ExpressionAttributeNames map[string]string `json:",omitempty"`
+}
+
 type DeleteRequest struct {
 	Key AttributeValue
 }
@@ -64,7 +140,7 @@ func Definitions(v interface{}) (d AttributeDefinitions, err error) {
 		if name == "" {
 			name = ft.Name
 		}
-		if !(options.Contains("hash") || options.Contains("range")) {
+		if !(options.Contains("hash") || options.Contains("range") || options.indexed()) {
 			// Ignore non-keys field if asking only for keys
 			continue
 		}
@@ -76,6 +152,52 @@ func Definitions(v interface{}) (d AttributeDefinitions, err error) {
 	return d, nil
 }
 
+// Returns the names of the attributes the given struct is stored as.
+func Attributes(v interface{}) (a []string, err error) {
+	t := reflect.Indirect(reflect.ValueOf(v)).Type()
+	if t.Kind() != reflect.Struct {
+		return nil, ErrValueStruct
+	}
+	for i := 0; i < t.NumField(); i++ {
+		ft := t.Field(i)
+		tag := ft.Tag.Get("dynamo")
+		if ft.Anonymous || ft.PkgPath != "" || tag == "-" {
+			// Ignore unusable fields
+			continue
+		}
+		name, options := parseTag(tag)
+		if options.Contains("inline") {
+			continue
+		}
+		if name == "" {
+			name = ft.Name
+		}
+		a = append(a, name)
+	}
+	return a, nil
+}
+
+// Returns the name of the time to live attribute of the given struct,
+// declared with the "ttl" tag option, or an empty string.
+func TimeToLive(v interface{}) (string, error) {
+	t := reflect.Indirect(reflect.ValueOf(v)).Type()
+	if t.Kind() != reflect.Struct {
+		return "", ErrValueStruct
+	}
+	for i := 0; i < t.NumField(); i++ {
+		ft := t.Field(i)
+		name, options := parseTag(ft.Tag.Get("dynamo"))
+		if !options.Contains("ttl") {
+			continue
+		}
+		if name == "" {
+			name = ft.Name
+		}
+		return name, nil
+	}
+	return "", nil
+}
+
 func typeGuess(t reflect.Type) string {
 	if t.Implements(textMarshalerType) {
 		return "S"
@@ -129,3 +251,152 @@ func Keys(v interface{}) (k KeySchema, err error) {
 	}
 	return k, nil
 }
+
+// Returns the global and local secondary indexes declared by the given
+// struct. Fields are declared as keys of an index with the "gsi" and "lsi"
+// tag options, optionally followed by the projection type of the index:
+//
+//	Email string    `dynamo:"email,gsi=ByEmail:hash:keys_only"`
+//	Date  time.Time `dynamo:"date,lsi=ByDate:range"`
+//
+// Non-key attributes are projected into an index with the "include" tag
+// option:
+//
+//	Name string `dynamo:"name,include=ByEmail"`
+func Indexes(v interface{}) (g []GlobalSecondaryIndex, l []LocalSecondaryIndex, err error) {
+	s := reflect.Indirect(reflect.ValueOf(v))
+	if s.Kind() != reflect.Struct {
+		return nil, nil, ErrValueStruct
+	}
+	t := s.Type()
+	type index struct {
+		global     bool
+		keys       KeySchema
+		projection Projection
+	}
+	var hash string
+	var names []string
+	indexes := make(map[string]*index)
+	included := make(map[string][]string)
+	for i := 0; i < s.NumField(); i++ {
+		ft := t.Field(i)
+		tag := ft.Tag.Get("dynamo")
+		name, options := parseTag(tag)
+		if name == "" {
+			name = ft.Name
+		}
+		if options.Contains("hash") {
+			hash = name
+		}
+		for _, kind := range []string{"gsi", "lsi"} {
+			for _, value := range options.Values(kind) {
+				parts := strings.Split(value, ":")
+				if len(parts) < 2 || len(parts) > 3 || parts[0] == "" {
+					return nil, nil, fmt.Errorf("dynamodb: invalid index option %s=%s", kind, value)
+				}
+				idx, present := indexes[parts[0]]
+				if !present {
+					idx = &index{global: kind == "gsi"}
+					indexes[parts[0]] = idx
+					names = append(names, parts[0])
+				}
+				if idx.global != (kind == "gsi") {
+					return nil, nil, fmt.Errorf("dynamodb: index %s is both global and local", parts[0])
+				}
+				switch parts[1] {
+				case "hash":
+					idx.keys = append(idx.keys, KeySchemaElement{AttributeName: name, KeyType: "HASH"})
+				case "range":
+					idx.keys = append(idx.keys, KeySchemaElement{AttributeName: name, KeyType: "RANGE"})
+				default:
+					return nil, nil, fmt.Errorf("dynamodb: invalid index option %s=%s", kind, value)
+				}
+				if len(parts) == 3 {
+					idx.projection.ProjectionType = strings.ToUpper(parts[2])
+				}
+			}
+		}
+		for _, value := range options.Values("include") {
+			included[value] = append(included[value], name)
+		}
+	}
+	for name := range included {
+		if _, present := indexes[name]; !present {
+			return nil, nil, fmt.Errorf("dynamodb: unknown index %s", name)
+		}
+	}
+	for _, name := range names {
+		idx := indexes[name]
+		idx.projection.NonKeyAttributes = included[name]
+		if idx.projection.ProjectionType == "" {
+			idx.projection.ProjectionType = "ALL"
+			if len(idx.projection.NonKeyAttributes) > 0 {
+				idx.projection.ProjectionType = "INCLUDE"
+			}
+		}
+		if !idx.global {
+			if hash == "" {
+				return nil, nil, fmt.Errorf("dynamodb: local index %s requires a hash key", name)
+			}
+			idx.keys = append(KeySchema{{AttributeName: hash, KeyType: "HASH"}}, idx.keys...)
+		}
+		sort.SliceStable(idx.keys, func(i, j int) bool {
+			return idx.keys[i].KeyType == "HASH" && idx.keys[j].KeyType != "HASH"
+		})
+		if err := idx.keys.validate(); err != nil {
+			return nil, nil, fmt.Errorf("dynamodb: index %s %s", name, err)
+		}
+		if idx.global {
+			g = append(g, GlobalSecondaryIndex{
+				IndexName:  name,
+				KeySchema:  idx.keys,
+				Projection: idx.projection,
+			})
+		} else {
+			l = append(l, LocalSecondaryIndex{
+				IndexName:  name,
+				KeySchema:  idx.keys,
+				Projection: idx.projection,
+			})
+		}
+	}
+	return g, l, nil
+}
+
+// Returns the key schema of the given index declared by the given struct.
+func IndexKeys(v interface{}, name string) (KeySchema, error) {
+	g, l, err := Indexes(v)
+	if err != nil {
+		return nil, err
+	}
+	for _, idx := range g {
+		if idx.IndexName == name {
+			return idx.KeySchema, nil
+		}
+	}
+	for _, idx := range l {
+		if idx.IndexName == name {
+			return idx.KeySchema, nil
+		}
+	}
+	return nil, fmt.Errorf("dynamodb: unknown index %s", name)
+}
+
+func (k KeySchema) validate() error {
+	switch {
+	case len(k) == 0 || k[0].KeyType != "HASH":
+		return errors.New("has no hash key")
+	case len(k) > 2 || (len(k) == 2 && k[1].KeyType != "RANGE"):
+		return errors.New("has too many keys")
+	}
+	return nil
+}
+
+func (o options) indexed() bool {
+	for _, value := range append(o.Values("gsi"), o.Values("lsi")...) {
+		if parts := strings.Split(value, ":"); len(parts) > 1 && (parts[1] == "hash" || parts[1] == "range") {
+			return true
+		}
+	}
+	return false
+}
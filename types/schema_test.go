@@ -49,3 +49,119 @@ func TestAttributeDefinitions(t *testing.T) {
 		t.Errorf("got %v wants %v", d, control)
 	}
 }
+
+func TestAttributes(t *testing.T) {
+	a, err := Attributes(&inlineTest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	control := []string{"string"}
+	if !reflect.DeepEqual(a, control) {
+		t.Errorf("got %v wants %v", a, control)
+	}
+}
+
+type indexed struct {
+	Hash   string `dynamo:"h,hash"`
+	Range  int    `dynamo:"r,range"`
+	Email  string `dynamo:"email,gsi=ByEmail:hash:keys_only"`
+	Name   string `dynamo:"name,gsi=ByName:hash,include=ByDate"`
+	Date   string `dynamo:"date,gsi=ByName:range,lsi=ByDate:range"`
+	Ignore string `dynamo:"ignore"`
+}
+
+func TestIndexes(t *testing.T) {
+	g, l, err := Indexes(&indexed{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	global := []GlobalSecondaryIndex{
+		{
+			IndexName: "ByEmail",
+			KeySchema: KeySchema{
+				{AttributeName: "email", KeyType: "HASH"},
+			},
+			Projection: Projection{ProjectionType: "KEYS_ONLY"},
+		},
+		{
+			IndexName: "ByName",
+			KeySchema: KeySchema{
+				{AttributeName: "name", KeyType: "HASH"},
+				{AttributeName: "date", KeyType: "RANGE"},
+			},
+			Projection: Projection{ProjectionType: "ALL"},
+		},
+	}
+	if !reflect.DeepEqual(g, global) {
+		t.Errorf("got %v wants %v", g, global)
+	}
+	local := []LocalSecondaryIndex{
+		{
+			IndexName: "ByDate",
+			KeySchema: KeySchema{
+				{AttributeName: "h", KeyType: "HASH"},
+				{AttributeName: "date", KeyType: "RANGE"},
+			},
+			Projection: Projection{
+				ProjectionType:   "INCLUDE",
+				NonKeyAttributes: []string{"name"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(l, local) {
+		t.Errorf("got %v wants %v", l, local)
+	}
+}
+
+func TestIndexDefinitions(t *testing.T) {
+	d, err := Definitions(&indexed{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, definition := range d {
+		names = append(names, definition.AttributeName)
+	}
+	control := []string{"h", "r", "email", "name", "date"}
+	if !reflect.DeepEqual(names, control) {
+		t.Errorf("got %v wants %v", names, control)
+	}
+}
+
+func TestInvalidIndexes(t *testing.T) {
+	for _, v := range []interface{}{
+		&struct {
+			Range int `dynamo:"r,gsi=ByRange:range"`
+		}{},
+		&struct {
+			Range int `dynamo:"r,lsi=ByRange:range"`
+		}{},
+		&struct {
+			Hash string `dynamo:"h,hash,gsi=Both:hash"`
+			Date string `dynamo:"d,lsi=Both:range"`
+		}{},
+		&struct {
+			Hash string `dynamo:"h,gsi=ByHash:key"`
+		}{},
+		&struct {
+			Hash string `dynamo:"h,gsi=ByHash:hash,include=Unknown"`
+		}{},
+	} {
+		if _, _, err := Indexes(v); err == nil {
+			t.Errorf("%T wasn't rejected", v)
+		}
+	}
+}
+
+func TestTimeToLiveAttribute(t *testing.T) {
+	name, err := TimeToLive(&ttlTest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "expires" {
+		t.Errorf("got %v wants %v", name, "expires")
+	}
+	if name, _ := TimeToLive(&schema{}); name != "" {
+		t.Errorf("got %v wants no attribute", name)
+	}
+}
@@ -0,0 +1,135 @@
+package types
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+)
+
+type Table struct {
+	ArchivalSummary           *ArchivalSummary
+	AttributeDefinitions      AttributeDefinitions
+	BillingModeSummary        *BillingModeSummary
+	CreationDateTime          Timestamp
+	DeletionProtectionEnabled bool
+	GlobalSecondaryIndexes    []GlobalSecondaryIndexDescription
+	GlobalTableVersion        string
+	ItemCount                 int
+	KeySchema                 KeySchema
+	LatestStreamArn           string
+	LatestStreamLabel         string
+	LocalSecondaryIndexes     []LocalSecondaryIndexDescription
+	OnDemandThroughput        *OnDemandThroughput
+	ProvisionedThroughput     ProvisionedThroughputDescription
+	Replicas                  []ReplicaDescription
+	RestoreSummary            *RestoreSummary
+	SSEDescription            *SSEDescription
+	StreamSpecification       *StreamSpecification
+	TableArn                  string
+	TableClassSummary         *TableClassSummary
+	TableId                   string
+	TableName                 string
+	TableSizeBytes            int
+	TableStatus               string
+}
+
+type ArchivalSummary struct {
+	ArchivalBackupArn string
+	ArchivalDateTime  Timestamp
+	ArchivalReason    string
+}
+
+type BillingModeSummary struct {
+	BillingMode                       string
+	LastUpdateToPayPerRequestDateTime Timestamp
+}
+
+type GlobalSecondaryIndexDescription struct {
+	Backfilling           bool
+	IndexArn              string
+	IndexName             string
+	IndexSizeBytes        int
+	IndexStatus           string
+	ItemCount             int
+	KeySchema             KeySchema
+	OnDemandThroughput    *OnDemandThroughput
+	Projection            Projection
+	ProvisionedThroughput ProvisionedThroughputDescription
+}
+
+type LocalSecondaryIndexDescription struct {
+	IndexArn       string
+	IndexName      string
+	IndexSizeBytes int
+	ItemCount      int
+	KeySchema      KeySchema
+	Projection     Projection
+}
+
+type OnDemandThroughput struct {
+	MaxReadRequestUnits  int
+	MaxWriteRequestUnits int
+}
+
+type ProvisionedThroughputDescription struct {
+	LastDecreaseDateTime   Timestamp
+	LastIncreaseDateTime   Timestamp
+	NumberOfDecreasesToday int
+	ReadCapacityUnits      int
+	WriteCapacityUnits     int
+}
+
+type ReplicaDescription struct {
+	KMSMasterKeyId              string
+	RegionName                  string
+	ReplicaInaccessibleDateTime Timestamp
+	ReplicaStatus               string
+	ReplicaStatusDescription    string
+	ReplicaTableClassSummary    *TableClassSummary
+}
+
+type RestoreSummary struct {
+	RestoreDateTime   Timestamp
+	RestoreInProgress bool
+	SourceBackupArn   string
+	SourceTableArn    string
+}
+
+type SSEDescription struct {
+	InaccessibleEncryptionDateTime Timestamp
+	KMSMasterKeyArn                string
+	SSEType                        string
+	Status                         string
+}
+
+type TableClassSummary struct {
+	LastUpdateDateTime Timestamp
+	TableClass         string
+}
+
+// A Timestamp is a time encoded as a number of seconds since the Unix
+// epoch, with a fractional part.
+type Timestamp struct {
+	time.Time
+}
+
+func (t *Timestamp) UnmarshalJSON(b []byte) error {
+	var seconds *float64
+	if err := json.Unmarshal(b, &seconds); err != nil {
+		return err
+	}
+	if seconds == nil {
+		t.Time = time.Time{}
+		return nil
+	}
+	sec, frac := math.Modf(*seconds)
+	t.Time = time.Unix(int64(sec), int64(math.Round(frac*1e3))*1e6).UTC()
+	return nil
+}
+
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(float64(t.UnixMilli()) / 1e3)
+}
@@ -0,0 +1,85 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+const describeTable = `{
+	"AttributeDefinitions": [{"AttributeName": "title", "AttributeType": "S"}],
+	"BillingModeSummary": {
+		"BillingMode": "PAY_PER_REQUEST",
+		"LastUpdateToPayPerRequestDateTime": 1.705420825565E9
+	},
+	"CreationDateTime": 1.386844530125E9,
+	"DeletionProtectionEnabled": true,
+	"GlobalSecondaryIndexes": [{
+		"IndexArn": "arn:aws:dynamodb:us-east-1:123456789012:table/papers/index/ByYear",
+		"IndexName": "ByYear",
+		"IndexSizeBytes": 1024,
+		"IndexStatus": "ACTIVE",
+		"ItemCount": 12,
+		"KeySchema": [{"AttributeName": "year", "KeyType": "HASH"}],
+		"Projection": {"ProjectionType": "KEYS_ONLY"},
+		"ProvisionedThroughput": {"NumberOfDecreasesToday": 0, "ReadCapacityUnits": 0, "WriteCapacityUnits": 0}
+	}],
+	"ItemCount": 12,
+	"KeySchema": [{"AttributeName": "title", "KeyType": "HASH"}],
+	"LatestStreamArn": "arn:aws:dynamodb:us-east-1:123456789012:table/papers/stream/2024-01-16T16:00:25.565",
+	"LatestStreamLabel": "2024-01-16T16:00:25.565",
+	"ProvisionedThroughput": {
+		"LastDecreaseDateTime": 1.7054208E9,
+		"NumberOfDecreasesToday": 1,
+		"ReadCapacityUnits": 0,
+		"WriteCapacityUnits": 0
+	},
+	"SSEDescription": {"SSEType": "KMS", "Status": "ENABLED"},
+	"StreamSpecification": {"StreamEnabled": true, "StreamViewType": "NEW_AND_OLD_IMAGES"},
+	"TableArn": "arn:aws:dynamodb:us-east-1:123456789012:table/papers",
+	"TableId": "0ba4a4d8-a1a2-4b5b-8b58-4c5b6e0c4e4d",
+	"TableName": "papers",
+	"TableSizeBytes": 4096,
+	"TableStatus": "ACTIVE"
+}`
+
+func TestTable(t *testing.T) {
+	var table Table
+	if err := json.Unmarshal([]byte(describeTable), &table); err != nil {
+		t.Fatal(err)
+	}
+	created := time.Date(2013, 12, 12, 10, 35, 30, 125e6, time.UTC)
+	if !table.CreationDateTime.Equal(created) {
+		t.Errorf("got %v wants %v", table.CreationDateTime, created)
+	}
+	if table.BillingModeSummary.BillingMode != "PAY_PER_REQUEST" {
+		t.Errorf("got %v", table.BillingModeSummary)
+	}
+	if table.GlobalSecondaryIndexes[0].Projection.ProjectionType != "KEYS_ONLY" {
+		t.Errorf("got %v", table.GlobalSecondaryIndexes)
+	}
+	if table.ProvisionedThroughput.LastDecreaseDateTime.Unix() != 1705420800 {
+		t.Errorf("got %v", table.ProvisionedThroughput)
+	}
+	if !table.ProvisionedThroughput.LastIncreaseDateTime.IsZero() {
+		t.Errorf("got %v", table.ProvisionedThroughput)
+	}
+	if table.SSEDescription.Status != "ENABLED" || table.TableId == "" || table.TableArn == "" {
+		t.Errorf("got %v", table)
+	}
+}
+
+func TestTimestamp(t *testing.T) {
+	ts := Timestamp{time.Date(2013, 12, 12, 10, 35, 30, 125e6, time.UTC)}
+	b, err := json.Marshal(ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var control Timestamp
+	if err := json.Unmarshal(b, &control); err != nil {
+		t.Fatal(err)
+	}
+	if !control.Equal(ts.Time) {
+		t.Errorf("got %v wants %v", control, ts)
+	}
+}
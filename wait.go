@@ -0,0 +1,62 @@
+package dynamodb
+
+import (
+	"context"
+	"github.com/cyberdelia/dynamodb/types"
+	"time"
+)
+
+// Statuses accepted by WaitForTable. TableDeleted isn't reported by
+// DynamoDB, it stands for a table that no longer exists.
+const (
+	TableActive  = "ACTIVE"
+	TableDeleted = "DELETED"
+)
+
+// Delays between two DescribeTable calls made by WaitForTable, doubling
+// after each call up to waitMaxDelay.
+var (
+	waitDelay    = time.Second
+	waitMaxDelay = 20 * time.Second
+)
+
+// Waits until the given table reaches the given status, polling it with
+// an increasing delay until ctx is done. Waiting for TableActive also waits
+// for every global secondary index of the table to be active, while waiting
+// for TableDeleted returns once the table no longer exists.
+func (s *Service) WaitForTable(ctx context.Context, tableName, status string) error {
+	delay := waitDelay
+	for {
+		table, err := s.describeTable(ctx, tableName)
+		switch {
+		case status == TableDeleted && IsError(err, "ResourceNotFoundException"):
+			return nil
+		case err != nil:
+			return err
+		case table.TableStatus == status && (status != TableActive || indexesActive(table)):
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > waitMaxDelay {
+			delay = waitMaxDelay
+		}
+	}
+}
+
+// Waits until the given table reaches the given status.
+func WaitForTable(ctx context.Context, tableName, status string) error {
+	return DefaultService.WaitForTable(ctx, tableName, status)
+}
+
+func indexesActive(table types.Table) bool {
+	for _, index := range table.GlobalSecondaryIndexes {
+		if index.IndexStatus != TableActive || index.Backfilling {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,83 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func init() {
+	waitDelay = time.Millisecond
+	waitMaxDelay = time.Millisecond
+}
+
+func TestWaitForTableActive(t *testing.T) {
+	var calls int
+	s := testService(func(action string, body map[string]interface{}) (int, interface{}) {
+		calls++
+		status, index := "CREATING", "CREATING"
+		if calls > 1 {
+			status = "ACTIVE"
+		}
+		if calls > 2 {
+			index = "ACTIVE"
+		}
+		return 200, map[string]interface{}{
+			"Table": map[string]interface{}{
+				"TableName":   "papers",
+				"TableStatus": status,
+				"GlobalSecondaryIndexes": []interface{}{
+					map[string]interface{}{"IndexName": "ByYear", "IndexStatus": index},
+				},
+			},
+		}
+	})
+	if err := s.WaitForTable(context.Background(), "papers", TableActive); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Errorf("got %d calls wants 3", calls)
+	}
+}
+
+func TestWaitForTableDeleted(t *testing.T) {
+	var calls int
+	s := testService(func(action string, body map[string]interface{}) (int, interface{}) {
+		calls++
+		if calls > 1 {
+			return 400, map[string]interface{}{
+				"__type":  "com.amazonaws.dynamodb.v20120810#ResourceNotFoundException",
+				"message": "Requested resource not found",
+			}
+		}
+		return 200, map[string]interface{}{
+			"Table": map[string]interface{}{
+				"TableName":   "papers",
+				"TableStatus": "DELETING",
+			},
+		}
+	})
+	if err := s.WaitForTable(context.Background(), "papers", TableDeleted); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WaitForTable(context.Background(), "papers", TableActive); !IsError(err, "ResourceNotFoundException") {
+		t.Errorf("got %v wants ResourceNotFoundException", err)
+	}
+}
+
+func TestWaitForTableCancel(t *testing.T) {
+	s := testService(func(action string, body map[string]interface{}) (int, interface{}) {
+		return 200, map[string]interface{}{
+			"Table": map[string]interface{}{
+				"TableName":   "papers",
+				"TableStatus": "CREATING",
+			},
+		}
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := s.WaitForTable(ctx, "papers", TableActive); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v wants %v", err, context.DeadlineExceeded)
+	}
+}